@@ -0,0 +1,104 @@
+package main
+
+import "fmt"
+
+type ServerGroup struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	UserIDs     []string               `json:"user_ids"`
+	Permissions map[string]interface{} `json:"permissions"`
+}
+
+type ServerUser struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Email           string `json:"email"`
+	Role            string `json:"role"`
+	ProfileImageUrl string `json:"profile_image_url"`
+}
+
+func getGroups() ([]ServerGroup, error) {
+	var groups []ServerGroup
+	err := apiRequest("GET", fmt.Sprintf("%s/api/v1/groups/", BASE_URL), nil, &groups)
+	return groups, err
+}
+
+func getUsers() ([]ServerUser, error) {
+	var users []ServerUser
+	err := apiRequest("GET", fmt.Sprintf("%s/api/v1/users/", BASE_URL), nil, &users)
+	return users, err
+}
+
+func resolveGroupIDs(refs []string, groups []ServerGroup) ([]string, error) {
+	ids := []string{}
+	for _, ref := range refs {
+		found := false
+		for _, group := range groups {
+			if ref == group.ID || ref == group.Name {
+				ids = append(ids, group.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("group %s not found on server", ref)
+		}
+	}
+	return ids, nil
+}
+
+func resolveUserIDs(refs []string, users []ServerUser) ([]string, error) {
+	ids := []string{}
+	for _, ref := range refs {
+		found := false
+		for _, user := range users {
+			if ref == user.ID || ref == user.Email {
+				ids = append(ids, user.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("user %s not found on server", ref)
+		}
+	}
+	return ids, nil
+}
+
+func resolveAccessControl(ac AccessControl) (interface{}, error) {
+	if ac.Public {
+		return nil, nil
+	}
+
+	var groups []ServerGroup
+	var users []ServerUser
+	var err error
+	if len(ac.Read.Groups) > 0 || len(ac.Write.Groups) > 0 {
+		if groups, err = getGroups(); err != nil {
+			return nil, err
+		}
+	}
+	if len(ac.Read.Users) > 0 || len(ac.Write.Users) > 0 {
+		if users, err = getUsers(); err != nil {
+			return nil, err
+		}
+	}
+
+	accessControl := make(map[string]interface{})
+	for name, grant := range map[string]AccessGrant{"read": ac.Read, "write": ac.Write} {
+		groupIDs, err := resolveGroupIDs(grant.Groups, groups)
+		if err != nil {
+			return nil, err
+		}
+		userIDs, err := resolveUserIDs(grant.Users, users)
+		if err != nil {
+			return nil, err
+		}
+		accessControl[name] = map[string][]string{
+			"group_ids": groupIDs,
+			"user_ids":  userIDs,
+		}
+	}
+	return accessControl, nil
+}
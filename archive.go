@@ -0,0 +1,131 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func isArchiveSource(source string) bool {
+	name := strings.ToLower(strings.SplitN(source, "?", 2)[0])
+	return strings.HasSuffix(name, ".zip") || strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+func extractZip(archivePath, target string) error {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, f := range archive.File {
+		name := filepath.Join(target, filepath.FromSlash(filepath.Clean("/"+f.Name)))
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(name, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		src, err := f.Open()
+		if err != nil {
+			return err
+		}
+		dst, err := os.Create(name)
+		if err != nil {
+			src.Close()
+			return err
+		}
+		_, err = io.Copy(dst, src)
+		src.Close()
+		dst.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downloadFile(rawUrl, target string, headers map[string]string) error {
+	req, err := http.NewRequest("GET", rawUrl, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch URL: %s: %s", rawUrl, resp.Status)
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
+
+func handleArchiveSource(source DocumentSource, manifestPath string) ([]string, string, error) {
+	tempDir, err := os.MkdirTemp("", "oictl_archive_")
+	if err != nil {
+		return nil, "", err
+	}
+	fail := func(err error) ([]string, string, error) {
+		os.RemoveAll(tempDir)
+		return nil, "", err
+	}
+
+	archivePath := source.Source
+	if isUrlSource(source.Source) {
+		archivePath = filepath.Join(tempDir, "download"+filepath.Ext(strings.SplitN(source.Source, "?", 2)[0]))
+		headers, err := urlHeaders(source)
+		if err != nil {
+			return fail(err)
+		}
+		if err := downloadFile(source.Source, archivePath, headers); err != nil {
+			return fail(err)
+		}
+	} else if !filepath.IsAbs(archivePath) {
+		archivePath = filepath.Join(filepath.Dir(manifestPath), archivePath)
+	}
+
+	extracted := filepath.Join(tempDir, "extracted")
+	if strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		err = extractZip(archivePath, extracted)
+	} else {
+		var file *os.File
+		if file, err = os.Open(archivePath); err == nil {
+			err = extractTarGz(file, extracted, 0)
+			file.Close()
+		}
+	}
+	if err != nil {
+		return fail(fmt.Errorf("failed to extract %s: %v", source.Source, err))
+	}
+
+	dirs := source.Dir
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	files, err := collectRepoFiles(extracted, dirs, source)
+	if err != nil {
+		return fail(err)
+	}
+	return files, tempDir, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import "fmt"
+
+type ArenaModel struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Name            string         `yaml:"name,omitempty"`
+		Description     string         `yaml:"description,omitempty"`
+		ProfileImageUrl string         `yaml:"profile_image_url,omitempty"`
+		Models          []string       `yaml:"models,omitempty"`
+		FilterMode      string         `yaml:"filterMode,omitempty"`
+		AccessControl   *AccessControl `yaml:"accessControl,omitempty"`
+	} `yaml:"spec"`
+}
+
+func getEvaluationConfig() (map[string]interface{}, []interface{}, error) {
+	config := make(map[string]interface{})
+	if err := apiRequest("GET", fmt.Sprintf("%s/api/v1/evaluations/config", BASE_URL), nil, &config); err != nil {
+		return nil, nil, err
+	}
+	models, _ := config["EVALUATION_ARENA_MODELS"].([]interface{})
+	return config, models, nil
+}
+
+func setEvaluationConfig(config map[string]interface{}, models []interface{}) error {
+	if models == nil {
+		models = []interface{}{}
+	}
+	config["EVALUATION_ARENA_MODELS"] = models
+	return apiRequest("POST", fmt.Sprintf("%s/api/v1/evaluations/config", BASE_URL), config, nil)
+}
+
+func arenaModelID(model interface{}) string {
+	fields, _ := model.(map[string]interface{})
+	id, _ := fields["id"].(string)
+	return id
+}
+
+func processArenaModel(config ArenaModel) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	switch config.Spec.FilterMode {
+	case "", "include", "exclude":
+	default:
+		return fmt.Errorf("arena model %s has unknown filter mode %s", config.Metadata.Name, config.Spec.FilterMode)
+	}
+	filterMode := config.Spec.FilterMode
+	if filterMode == "" {
+		filterMode = "include"
+	}
+
+	name := config.Spec.Name
+	if name == "" {
+		name = config.Metadata.Name
+	}
+	profileImageUrl := config.Spec.ProfileImageUrl
+	if profileImageUrl == "" {
+		profileImageUrl = "/favicon.png"
+	}
+	meta := map[string]interface{}{
+		"profile_image_url": profileImageUrl,
+		"description":       config.Spec.Description,
+		"model_ids":         nil,
+		"filter_mode":       filterMode,
+		"access_control":    nil,
+	}
+	if len(config.Spec.Models) > 0 {
+		meta["model_ids"] = config.Spec.Models
+	}
+	if config.Spec.AccessControl != nil {
+		accessControl, err := resolveAccessControl(*config.Spec.AccessControl)
+		if err != nil {
+			return err
+		}
+		meta["access_control"] = accessControl
+	}
+	model := map[string]interface{}{
+		"id":   config.Metadata.Name,
+		"name": name,
+		"meta": meta,
+	}
+
+	evaluation, models, err := getEvaluationConfig()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i, existing := range models {
+		if arenaModelID(existing) == config.Metadata.Name {
+			models[i] = model
+			replaced = true
+		}
+	}
+	if !replaced {
+		models = append(models, model)
+	}
+	evaluation["ENABLE_EVALUATION_ARENA_MODELS"] = true
+	return setEvaluationConfig(evaluation, models)
+}
+
+func deleteArenaModel(config ArenaModel) error {
+	evaluation, models, err := getEvaluationConfig()
+	if err != nil {
+		return err
+	}
+	kept := []interface{}{}
+	for _, existing := range models {
+		if arenaModelID(existing) != config.Metadata.Name {
+			kept = append(kept, existing)
+		}
+	}
+	if len(kept) == len(models) {
+		return nil
+	}
+	return setEvaluationConfig(evaluation, kept)
+}
@@ -0,0 +1,85 @@
+package main
+
+import "fmt"
+
+type AudioEngine struct {
+	Engine    *string `yaml:"engine,omitempty"`
+	BaseUrl   string  `yaml:"baseUrl,omitempty"`
+	ApiKey    string  `yaml:"apiKey,omitempty"`
+	ApiKeyEnv string  `yaml:"apiKeyEnv,omitempty"`
+	Model     string  `yaml:"model,omitempty"`
+	Voice     string  `yaml:"voice,omitempty"`
+	SplitOn   string  `yaml:"splitOn,omitempty"`
+}
+
+type AudioConfig struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		TTS *AudioEngine `yaml:"tts,omitempty"`
+		STT *AudioEngine `yaml:"stt,omitempty"`
+	} `yaml:"spec"`
+}
+
+func audioFields(engine *AudioEngine, engines []string) (map[string]interface{}, error) {
+	if engine == nil {
+		return nil, nil
+	}
+	name := ""
+	if engine.Engine != nil {
+		name = *engine.Engine
+	}
+	if !containsString(engines, name) {
+		return nil, fmt.Errorf("unknown audio engine %s", name)
+	}
+	key, err := resolveApiKey(engine.ApiKey, engine.ApiKeyEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{
+		"OPENAI_API_BASE_URL": engine.BaseUrl,
+		"MODEL":               engine.Model,
+		"ENGINE":              engine.Engine,
+	}
+	switch name {
+	case "openai":
+		fields["OPENAI_API_KEY"] = key
+	case "deepgram":
+		fields["DEEPGRAM_API_KEY"] = key
+	default:
+		fields["API_KEY"] = key
+	}
+	return fields, nil
+}
+
+func processAudioConfig(config AudioConfig) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	tts, err := audioFields(config.Spec.TTS, []string{"", "openai", "elevenlabs", "azure", "transformers"})
+	if err != nil {
+		return fmt.Errorf("tts: %v", err)
+	}
+	if tts != nil {
+		tts["VOICE"] = config.Spec.TTS.Voice
+		tts["SPLIT_ON"] = config.Spec.TTS.SplitOn
+	}
+	stt, err := audioFields(config.Spec.STT, []string{"", "openai", "web", "deepgram"})
+	if err != nil {
+		return fmt.Errorf("stt: %v", err)
+	}
+	if stt != nil {
+		delete(stt, "API_KEY")
+	}
+
+	fields := map[string]interface{}{}
+	if tts != nil {
+		fields["tts"] = tts
+	}
+	if stt != nil {
+		fields["stt"] = stt
+	}
+	return updateServerConfig("/api/v1/audio/config", "/api/v1/audio/config/update", fields)
+}
@@ -0,0 +1,75 @@
+package main
+
+import "fmt"
+
+type AuthProvider struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Type    string `yaml:"type"`
+		Enabled *bool  `yaml:"enabled,omitempty"`
+		LDAP    struct {
+			Label                string `yaml:"label,omitempty"`
+			Host                 string `yaml:"host,omitempty"`
+			Port                 *int   `yaml:"port,omitempty"`
+			UseTls               *bool  `yaml:"useTls,omitempty"`
+			CertificatePath      string `yaml:"certificatePath,omitempty"`
+			Ciphers              string `yaml:"ciphers,omitempty"`
+			AppDn                string `yaml:"appDn,omitempty"`
+			AppDnPassword        string `yaml:"appDnPassword,omitempty"`
+			SearchBase           string `yaml:"searchBase,omitempty"`
+			SearchFilters        string `yaml:"searchFilters,omitempty"`
+			AttributeForMail     string `yaml:"attributeForMail,omitempty"`
+			AttributeForUsername string `yaml:"attributeForUsername,omitempty"`
+		} `yaml:"ldap,omitempty"`
+	} `yaml:"spec"`
+}
+
+func processLdapProvider(config AuthProvider) error {
+	ldap := config.Spec.LDAP
+	registerSecret(ldap.AppDnPassword)
+	server := map[string]interface{}{
+		"label":                  ldap.Label,
+		"host":                   ldap.Host,
+		"port":                   ldap.Port,
+		"use_tls":                ldap.UseTls,
+		"certificate_path":       ldap.CertificatePath,
+		"ciphers":                ldap.Ciphers,
+		"app_dn":                 ldap.AppDn,
+		"app_dn_password":        ldap.AppDnPassword,
+		"search_base":            ldap.SearchBase,
+		"search_filters":         ldap.SearchFilters,
+		"attribute_for_mail":     ldap.AttributeForMail,
+		"attribute_for_username": ldap.AttributeForUsername,
+	}
+	err := updateServerConfig("/api/v1/auths/admin/config/ldap/server", "/api/v1/auths/admin/config/ldap/server", server)
+	if err != nil {
+		return err
+	}
+
+	enabled := config.Spec.Enabled == nil || *config.Spec.Enabled
+	payload := map[string]bool{"enable_ldap": enabled}
+	return apiRequest("POST", fmt.Sprintf("%s/api/v1/auths/admin/config/ldap", BASE_URL), payload, nil)
+}
+
+func processAuthProvider(config AuthProvider) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	switch config.Spec.Type {
+	case "ldap":
+		return processLdapProvider(config)
+	case "oauth", "oidc":
+		return fmt.Errorf("auth provider %s: Open WebUI reads OAuth/OIDC settings from its environment (OAUTH_CLIENT_ID, OPENID_PROVIDER_URL, ...), they cannot be set through the admin API", config.Metadata.Name)
+	}
+	return fmt.Errorf("auth provider %s has unknown type %s", config.Metadata.Name, config.Spec.Type)
+}
+
+func deleteAuthProvider(config AuthProvider) error {
+	if config.Spec.Type != "ldap" {
+		return fmt.Errorf("deleting %s auth providers is not supported", config.Spec.Type)
+	}
+	payload := map[string]bool{"enable_ldap": false}
+	return apiRequest("POST", fmt.Sprintf("%s/api/v1/auths/admin/config/ldap", BASE_URL), payload, nil)
+}
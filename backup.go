@@ -0,0 +1,252 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+type BackupIndex struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name    string `yaml:"name"`
+		Created string `yaml:"created"`
+		Server  string `yaml:"server"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Models    int  `yaml:"models"`
+		Prompts   int  `yaml:"prompts"`
+		Tools     int  `yaml:"tools"`
+		Documents int  `yaml:"documents"`
+		Files     int  `yaml:"files"`
+		WithFiles bool `yaml:"withFiles"`
+	} `yaml:"spec"`
+}
+
+type ServerFile struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	Meta     struct {
+		Name string `json:"name"`
+	} `json:"meta"`
+}
+
+func getModels() ([]ServerModel, error) {
+	var models []ServerModel
+	err := apiRequest("GET", fmt.Sprintf("%s/api/v1/models/", BASE_URL), nil, &models)
+	return models, err
+}
+
+func getRawList(endpoint string) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	err := apiRequest("GET", fmt.Sprintf("%s%s", BASE_URL, endpoint), nil, &items)
+	return items, err
+}
+
+func downloadFileContent(id string) ([]byte, error) {
+	var content bytes.Buffer
+	if err := copyFileContent(id, &content); err != nil {
+		return nil, err
+	}
+	return content.Bytes(), nil
+}
+
+func copyFileContent(id string, w io.Writer) error {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/files/%s/content", BASE_URL, id), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", TOKEN))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to download file %s: %s - %s", id, resp.Status, string(respBody))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func safeArchiveName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(name)
+	if name == "" {
+		name = "unnamed"
+	}
+	return name
+}
+
+func addToArchive(tw *tar.Writer, name string, content []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
+
+func addManifest(tw *tar.Writer, dir, name string, manifest interface{}, modTime time.Time) error {
+	content, err := marshalManifest(manifest)
+	if err != nil {
+		return err
+	}
+	return addToArchive(tw, path.Join(dir, safeArchiveName(name)+".yaml"), content, modTime)
+}
+
+func rawItems(items []json.RawMessage) []map[string]interface{} {
+	fields := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		json.Unmarshal(item, &fields[i])
+	}
+	return fields
+}
+
+func writeBackup(output string, includeFiles bool) (BackupIndex, error) {
+	file, err := os.Create(output)
+	if err != nil {
+		return BackupIndex{}, err
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	index, err := writeBackupEntries(tw, output, includeFiles)
+	for _, closer := range []io.Closer{tw, gz, file} {
+		if closeErr := closer.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write %s: %v", output, closeErr)
+		}
+	}
+	return index, err
+}
+
+func writeBackupEntries(tw *tar.Writer, output string, includeFiles bool) (BackupIndex, error) {
+	var index BackupIndex
+	now := time.Now().UTC()
+	index.Kind = "Backup"
+	index.Metadata.Name = strings.TrimSuffix(path.Base(output), ".tar.gz")
+	index.Metadata.Created = now.Format(time.RFC3339)
+	index.Metadata.Server = BASE_URL
+	index.Spec.WithFiles = includeFiles
+
+	models, err := getModels()
+	if err != nil {
+		return index, err
+	}
+	prompts, err := getRawList("/api/v1/prompts/")
+	if err != nil {
+		return index, err
+	}
+	tools, err := getRawList("/api/v1/tools/export")
+	if err != nil {
+		return index, err
+	}
+	rawDocuments, err := getRawList("/api/v1/documents/")
+	if err != nil {
+		return index, err
+	}
+	documents := make([]Document, len(rawDocuments))
+	for i, raw := range rawDocuments {
+		json.Unmarshal(raw, &documents[i])
+	}
+	groups, users, err := accessLookups()
+	if err != nil {
+		return index, err
+	}
+
+	for _, model := range models {
+		if err := addManifest(tw, "models", model.ID, modelToManifest(model, groups, users), now); err != nil {
+			return index, err
+		}
+	}
+	for _, item := range rawItems(prompts) {
+		prompt := promptToManifest(item)
+		if err := addManifest(tw, "prompts", prompt.Metadata.Name, prompt, now); err != nil {
+			return index, err
+		}
+	}
+	for _, item := range rawItems(tools) {
+		tool := toolToManifest(item)
+		if err := addManifest(tw, "tools", tool.Metadata.Name, tool, now); err != nil {
+			return index, err
+		}
+	}
+	for i, manifest := range documentsToManifests(documents) {
+		if err := addManifest(tw, "documents", fmt.Sprintf("%s-%d", manifest.Metadata.Name, i+1), manifest, now); err != nil {
+			return index, err
+		}
+	}
+	index.Spec.Models = len(models)
+	index.Spec.Prompts = len(prompts)
+	index.Spec.Tools = len(tools)
+	index.Spec.Documents = len(documents)
+
+	if includeFiles {
+		var files []ServerFile
+		if err := apiRequest("GET", fmt.Sprintf("%s/api/v1/files/", BASE_URL), nil, &files); err != nil {
+			return index, err
+		}
+		for _, doc := range documents {
+			for _, f := range files {
+				if f.Filename != doc.Filename && f.Meta.Name != doc.Filename {
+					continue
+				}
+				content, err := downloadFileContent(f.ID)
+				if err != nil {
+					logf("Error downloading document %s: %v\n", doc.Name, err)
+					break
+				}
+				if err := addToArchive(tw, documentArchivePath(doc), content, now); err != nil {
+					return index, err
+				}
+				index.Spec.Files++
+				break
+			}
+		}
+	}
+
+	content, err := marshalManifest(index)
+	if err != nil {
+		return index, err
+	}
+	return index, addToArchive(tw, "backup.yaml", content, now)
+}
+
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := fs.String("output", "", "archive to write (default oictl-backup-<timestamp>.tar.gz)")
+	fs.StringVar(output, "o", "", "shorthand for --output")
+	includeFiles := fs.Bool("include-files", false, "also download document file contents")
+	fs.Parse(args)
+
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("oictl-backup-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	index, err := writeBackup(*output, *includeFiles)
+	if err != nil {
+		os.Remove(*output)
+		return err
+	}
+	logf("Backup written to %s (%d models, %d prompts, %d tools, %d documents, %d files)\n",
+		*output, index.Spec.Models, index.Spec.Prompts, index.Spec.Tools, index.Spec.Documents, index.Spec.Files)
+	return nil
+}
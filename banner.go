@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type Banner struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Type        string `yaml:"type"`
+		Title       string `yaml:"title,omitempty"`
+		Content     string `yaml:"content"`
+		Dismissible *bool  `yaml:"dismissible,omitempty"`
+		Timestamp   int64  `yaml:"timestamp,omitempty"`
+	} `yaml:"spec"`
+}
+
+type ServerBanner struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Title       string `json:"title,omitempty"`
+	Content     string `json:"content"`
+	Dismissible bool   `json:"dismissible"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+func getBanners() ([]ServerBanner, error) {
+	banners := []ServerBanner{}
+	err := apiRequest("GET", fmt.Sprintf("%s/api/v1/configs/banners", BASE_URL), nil, &banners)
+	return banners, err
+}
+
+func setBanners(banners []ServerBanner) error {
+	payload := map[string]interface{}{"banners": banners}
+	return apiRequest("POST", fmt.Sprintf("%s/api/v1/configs/banners", BASE_URL), payload, nil)
+}
+
+func processBanner(config Banner) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	switch config.Spec.Type {
+	case "info", "success", "warning", "error":
+	default:
+		return fmt.Errorf("banner %s has unknown type %s", config.Metadata.Name, config.Spec.Type)
+	}
+
+	banners, err := getBanners()
+	if err != nil {
+		return err
+	}
+	banner := ServerBanner{
+		ID:          config.Metadata.Name,
+		Type:        config.Spec.Type,
+		Title:       config.Spec.Title,
+		Content:     config.Spec.Content,
+		Dismissible: config.Spec.Dismissible == nil || *config.Spec.Dismissible,
+		Timestamp:   config.Spec.Timestamp,
+	}
+
+	found := false
+	for i, existing := range banners {
+		if existing.ID != banner.ID {
+			continue
+		}
+		if banner.Timestamp == 0 {
+			banner.Timestamp = existing.Timestamp
+		}
+		banners[i] = banner
+		found = true
+	}
+	if !found {
+		if banner.Timestamp == 0 {
+			banner.Timestamp = time.Now().Unix()
+		}
+		banners = append(banners, banner)
+	}
+	return setBanners(banners)
+}
+
+func deleteBanner(config Banner) error {
+	banners, err := getBanners()
+	if err != nil {
+		return err
+	}
+	kept := []ServerBanner{}
+	for _, banner := range banners {
+		if banner.ID != config.Metadata.Name {
+			kept = append(kept, banner)
+		}
+	}
+	if len(kept) == len(banners) {
+		return nil
+	}
+	return setBanners(kept)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type ChatArchive struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		File string `yaml:"file"`
+		User string `yaml:"user,omitempty"`
+	} `yaml:"spec"`
+}
+
+func readChatExport(path string) ([]map[string]interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var chats []map[string]interface{}
+	if err := json.Unmarshal(content, &chats); err != nil {
+		var chat map[string]interface{}
+		if json.Unmarshal(content, &chat) != nil {
+			return nil, fmt.Errorf("%s is not an Open WebUI chat export: %v", path, err)
+		}
+		chats = append(chats, chat)
+	}
+	return chats, nil
+}
+
+func currentUser() (ServerUser, error) {
+	var user ServerUser
+	err := apiRequest("GET", fmt.Sprintf("%s/api/v1/auths/", BASE_URL), nil, &user)
+	return user, err
+}
+
+func checkChatUser(user string) error {
+	if user == "" {
+		return nil
+	}
+	current, err := currentUser()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(current.Email, user) && current.ID != user {
+		return fmt.Errorf("the token belongs to %s, not %s; chats are imported for the token's user", current.Email, user)
+	}
+	return nil
+}
+
+func importChats(chats []map[string]interface{}) (int, int, error) {
+	var existing []struct {
+		Meta map[string]interface{} `json:"meta"`
+	}
+	if err := apiRequest("GET", fmt.Sprintf("%s/api/v1/chats/all", BASE_URL), nil, &existing); err != nil {
+		return 0, 0, err
+	}
+	importedIDs := make(map[string]bool)
+	for _, chat := range existing {
+		if id, ok := chat.Meta["imported_id"].(string); ok {
+			importedIDs[id] = true
+		}
+	}
+
+	imported, skipped := 0, 0
+	for _, chat := range chats {
+		id, _ := chat["id"].(string)
+		if id != "" && importedIDs[id] {
+			skipped++
+			continue
+		}
+		content, ok := chat["chat"].(map[string]interface{})
+		if !ok {
+			return imported, skipped, fmt.Errorf("chat %s has no chat content", id)
+		}
+		if _, ok := content["title"]; !ok {
+			content["title"] = chat["title"]
+		}
+
+		meta, _ := chat["meta"].(map[string]interface{})
+		if meta == nil {
+			meta = make(map[string]interface{})
+		}
+		if id != "" {
+			meta["imported_id"] = id
+		}
+		payload := map[string]interface{}{
+			"chat":      content,
+			"meta":      meta,
+			"pinned":    chat["pinned"] == true,
+			"folder_id": nil,
+		}
+		if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/chats/import", BASE_URL), payload, nil); err != nil {
+			return imported, skipped, fmt.Errorf("failed to import chat %s: %v", id, err)
+		}
+		imported++
+		logf("\rChats imported: %d", imported)
+	}
+	if imported > 0 {
+		logf("\n")
+	}
+	return imported, skipped, nil
+}
+
+func processChatArchive(config ChatArchive, manifestPath string) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+	if config.Spec.File == "" {
+		return fmt.Errorf("chat archive %s needs spec.file", config.Metadata.Name)
+	}
+
+	file := config.Spec.File
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(manifestPath), file)
+	}
+	chats, err := readChatExport(file)
+	if err != nil {
+		return err
+	}
+	if err := checkChatUser(config.Spec.User); err != nil {
+		return err
+	}
+	_, skipped, err := importChats(chats)
+	if skipped > 0 {
+		logf("Chat archive %s: %d chats already imported\n", config.Metadata.Name, skipped)
+	}
+	return err
+}
+
+func runChat(args []string) error {
+	fs := flag.NewFlagSet("chat", flag.ExitOnError)
+	user := fs.String("user", "", "email of the user the chats are imported for, checked against the token")
+	contextName := fs.String("context", "", "context name or server URL to import into")
+	positional := parseArgs(fs, args)
+
+	if len(positional) != 2 || positional[0] != "import" {
+		return fmt.Errorf("usage: oictl chat import <export.json> [--user email] [--context name]")
+	}
+	chats, err := readChatExport(positional[1])
+	if err != nil {
+		return err
+	}
+
+	run := func() error {
+		if err := checkChatUser(*user); err != nil {
+			return err
+		}
+		imported, skipped, err := importChats(chats)
+		if err != nil {
+			return err
+		}
+		logf("Imported %d chats, %d already imported\n", imported, skipped)
+		return nil
+	}
+
+	if *contextName != "" {
+		c, err := findContext(*contextName)
+		if err != nil {
+			return err
+		}
+		return withContext(c, run)
+	}
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+	return run()
+}
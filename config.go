@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type InstanceConfig struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		DefaultUserRole        string             `yaml:"defaultUserRole,omitempty"`
+		EnableSignup           *bool              `yaml:"enableSignup,omitempty"`
+		EnableApiKeys          *bool              `yaml:"enableApiKeys,omitempty"`
+		EnableCommunitySharing *bool              `yaml:"enableCommunitySharing,omitempty"`
+		EnableMessageRating    *bool              `yaml:"enableMessageRating,omitempty"`
+		ShowAdminDetails       *bool              `yaml:"showAdminDetails,omitempty"`
+		JwtExpiresIn           string             `yaml:"jwtExpiresIn,omitempty"`
+		WebuiUrl               string             `yaml:"webuiUrl,omitempty"`
+		WebhookUrl             *string            `yaml:"webhookUrl,omitempty"`
+		DefaultModels          []string           `yaml:"defaultModels,omitempty"`
+		ModelOrder             []string           `yaml:"modelOrder,omitempty"`
+		PromptSuggestions      []SuggestionPrompt `yaml:"promptSuggestions,omitempty"`
+	} `yaml:"spec"`
+}
+
+func pruneUnset(fields map[string]interface{}) {
+	for key, value := range fields {
+		switch v := value.(type) {
+		case nil:
+			delete(fields, key)
+		case string:
+			if v == "" {
+				delete(fields, key)
+			}
+		case map[string]interface{}:
+			pruneUnset(v)
+			if len(v) == 0 {
+				delete(fields, key)
+			}
+		default:
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+				delete(fields, key)
+			}
+		}
+	}
+}
+
+func updateServerConfig(endpoint, updateEndpoint string, fields map[string]interface{}) error {
+	pruneUnset(fields)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	current := make(map[string]interface{})
+	if err := apiRequest("GET", fmt.Sprintf("%s%s", BASE_URL, endpoint), nil, &current); err != nil {
+		return err
+	}
+	mergeValues(current, fields)
+	return apiRequest("POST", fmt.Sprintf("%s%s", BASE_URL, updateEndpoint), current, nil)
+}
+
+func processConfig(config InstanceConfig) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	switch config.Spec.DefaultUserRole {
+	case "", "admin", "user", "pending":
+	default:
+		return fmt.Errorf("unknown default user role %s", config.Spec.DefaultUserRole)
+	}
+
+	fields := map[string]interface{}{
+		"DEFAULT_USER_ROLE":        config.Spec.DefaultUserRole,
+		"JWT_EXPIRES_IN":           config.Spec.JwtExpiresIn,
+		"WEBUI_URL":                config.Spec.WebuiUrl,
+		"ENABLE_SIGNUP":            config.Spec.EnableSignup,
+		"ENABLE_API_KEY":           config.Spec.EnableApiKeys,
+		"ENABLE_COMMUNITY_SHARING": config.Spec.EnableCommunitySharing,
+		"ENABLE_MESSAGE_RATING":    config.Spec.EnableMessageRating,
+		"SHOW_ADMIN_DETAILS":       config.Spec.ShowAdminDetails,
+	}
+	if err := updateServerConfig("/api/v1/auths/admin/config", "/api/v1/auths/admin/config", fields); err != nil {
+		return err
+	}
+
+	if config.Spec.WebhookUrl != nil {
+		payload := map[string]string{"url": *config.Spec.WebhookUrl}
+		if err := apiRequest("POST", fmt.Sprintf("%s/api/webhook", BASE_URL), payload, nil); err != nil {
+			return err
+		}
+	}
+
+	if config.Spec.PromptSuggestions != nil {
+		suggestions := []map[string]interface{}{}
+		for _, suggestion := range config.Spec.PromptSuggestions {
+			title := suggestion.Title
+			if title == nil {
+				title = []string{}
+			}
+			suggestions = append(suggestions, map[string]interface{}{"title": title, "content": suggestion.Content})
+		}
+		payload := map[string]interface{}{"suggestions": suggestions}
+		if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/configs/suggestions", BASE_URL), payload, nil); err != nil {
+			return err
+		}
+	}
+
+	fields = make(map[string]interface{})
+	if config.Spec.DefaultModels != nil {
+		defaultModels := strings.Join(config.Spec.DefaultModels, ",")
+		fields["DEFAULT_MODELS"] = &defaultModels
+	}
+	if config.Spec.ModelOrder != nil {
+		fields["MODEL_ORDER_LIST"] = config.Spec.ModelOrder
+	}
+	return updateServerConfig("/api/v1/configs/models", "/api/v1/configs/models", fields)
+}
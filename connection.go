@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Connection struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Type      string   `yaml:"type"`
+		URL       string   `yaml:"url"`
+		ApiKey    string   `yaml:"apiKey,omitempty"`
+		ApiKeyEnv string   `yaml:"apiKeyEnv,omitempty"`
+		Enabled   *bool    `yaml:"enabled,omitempty"`
+		PrefixID  string   `yaml:"prefixId,omitempty"`
+		Models    []string `yaml:"models,omitempty"`
+		Tags      []string `yaml:"tags,omitempty"`
+	} `yaml:"spec"`
+}
+
+type connectionConfig struct {
+	endpoint string
+	prefix   string
+	urlsKey  string
+	fields   map[string]interface{}
+	urls     []interface{}
+	keys     []interface{}
+	configs  map[string]interface{}
+}
+
+func getConnectionConfig(connectionType string) (*connectionConfig, error) {
+	var c connectionConfig
+	switch connectionType {
+	case "openai", "":
+		c.endpoint, c.prefix, c.urlsKey = "/openai/config", "OPENAI", "OPENAI_API_BASE_URLS"
+	case "ollama":
+		c.endpoint, c.prefix, c.urlsKey = "/ollama/config", "OLLAMA", "OLLAMA_BASE_URLS"
+	default:
+		return nil, fmt.Errorf("unknown connection type %s", connectionType)
+	}
+
+	c.fields = make(map[string]interface{})
+	if err := apiRequest("GET", fmt.Sprintf("%s%s", BASE_URL, c.endpoint), nil, &c.fields); err != nil {
+		return nil, err
+	}
+	c.urls, _ = c.fields[c.urlsKey].([]interface{})
+	c.keys, _ = c.fields["OPENAI_API_KEYS"].([]interface{})
+	c.configs, _ = c.fields[c.prefix+"_API_CONFIGS"].(map[string]interface{})
+	if c.configs == nil {
+		c.configs = make(map[string]interface{})
+	}
+	return &c, nil
+}
+
+func (c *connectionConfig) index(url string) int {
+	for i, existing := range c.urls {
+		if s, ok := existing.(string); ok && strings.TrimSuffix(s, "/") == strings.TrimSuffix(url, "/") {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *connectionConfig) save() error {
+	if c.urls == nil {
+		c.urls = []interface{}{}
+	}
+	c.fields[c.urlsKey] = c.urls
+	c.fields[c.prefix+"_API_CONFIGS"] = c.configs
+	if c.prefix == "OPENAI" {
+		if c.keys == nil {
+			c.keys = []interface{}{}
+		}
+		c.fields["OPENAI_API_KEYS"] = c.keys
+	}
+	return apiRequest("POST", fmt.Sprintf("%s%s/update", BASE_URL, c.endpoint), c.fields, nil)
+}
+
+func processConnection(config Connection) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+	if config.Spec.URL == "" {
+		return fmt.Errorf("connection %s needs spec.url", config.Metadata.Name)
+	}
+
+	c, err := getConnectionConfig(config.Spec.Type)
+	if err != nil {
+		return err
+	}
+	key, err := resolveApiKey(config.Spec.ApiKey, config.Spec.ApiKeyEnv)
+	if err != nil {
+		return err
+	}
+	if key != "" && c.prefix == "OLLAMA" {
+		return fmt.Errorf("connection %s: Ollama connections do not use an API key", config.Metadata.Name)
+	}
+
+	i := c.index(config.Spec.URL)
+	if i < 0 {
+		i = len(c.urls)
+		c.urls = append(c.urls, config.Spec.URL)
+	}
+	if c.prefix == "OPENAI" {
+		for len(c.keys) < len(c.urls) {
+			c.keys = append(c.keys, "")
+		}
+		if key != "" {
+			c.keys[i] = key
+		}
+	}
+
+	models := config.Spec.Models
+	if models == nil {
+		models = []string{}
+	}
+	tags := config.Spec.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	c.configs[strconv.Itoa(i)] = map[string]interface{}{
+		"enable":    config.Spec.Enabled == nil || *config.Spec.Enabled,
+		"prefix_id": config.Spec.PrefixID,
+		"model_ids": models,
+		"tags":      tags,
+	}
+	c.fields["ENABLE_"+c.prefix+"_API"] = true
+	return c.save()
+}
+
+func deleteConnection(config Connection) error {
+	c, err := getConnectionConfig(config.Spec.Type)
+	if err != nil {
+		return err
+	}
+	i := c.index(config.Spec.URL)
+	if i < 0 {
+		return nil
+	}
+
+	c.urls = append(c.urls[:i], c.urls[i+1:]...)
+	if i < len(c.keys) {
+		c.keys = append(c.keys[:i], c.keys[i+1:]...)
+	}
+	configs := make(map[string]interface{})
+	for idx, value := range c.configs {
+		n, err := strconv.Atoi(idx)
+		switch {
+		case err != nil || n < i:
+			configs[idx] = value
+		case n > i:
+			configs[strconv.Itoa(n-1)] = value
+		}
+	}
+	c.configs = configs
+	return c.save()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Context struct {
+	Name     string `yaml:"name"`
+	Server   string `yaml:"server"`
+	Token    string `yaml:"token,omitempty"`
+	TokenEnv string `yaml:"tokenEnv,omitempty"`
+}
+
+type Config struct {
+	CurrentContext string    `yaml:"currentContext,omitempty"`
+	Contexts       []Context `yaml:"contexts"`
+}
+
+func configPath() string {
+	if path := os.Getenv("OICTL_CONFIG"); path != "" {
+		return path
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".oictl", "config.yaml")
+}
+
+func loadConfig() (Config, error) {
+	var config Config
+	content, err := os.ReadFile(configPath())
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("failed to parse %s: %v", configPath(), err)
+	}
+	return config, nil
+}
+
+func (c Context) token() string {
+	if c.TokenEnv != "" {
+		return os.Getenv(c.TokenEnv)
+	}
+	if c.Token != "" {
+		return c.Token
+	}
+	return os.Getenv("OI_TOKEN")
+}
+
+func findContext(name string) (Context, error) {
+	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+		return Context{Name: name, Server: strings.TrimSuffix(name, "/")}, nil
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return Context{}, err
+	}
+	for _, c := range config.Contexts {
+		if c.Name == name {
+			c.Server = strings.TrimSuffix(c.Server, "/")
+			return c, nil
+		}
+	}
+	return Context{}, fmt.Errorf("context %s not found in %s", name, configPath())
+}
+
+func withContext(c Context, fn func() error) error {
+	previousUrl, previousToken := BASE_URL, TOKEN
+	BASE_URL, TOKEN = c.Server, c.token()
+	registerSecret(TOKEN)
+	defer func() {
+		BASE_URL, TOKEN = previousUrl, previousToken
+	}()
+
+	if TOKEN == "" {
+		return fmt.Errorf("no token configured for context %s", c.Name)
+	}
+	return fn()
+}
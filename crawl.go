@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const crawlUserAgent = "oictl"
+
+var htmlLinkPattern = regexp.MustCompile(`(?i)<a\b[^>]*\bhref\s*=\s*["']([^"'#]+)`)
+
+type robotsRules struct {
+	allow    []string
+	disallow []string
+	delay    time.Duration
+}
+
+func (r robotsRules) allowed(p string) bool {
+	match, allowed := -1, true
+	for _, rule := range r.disallow {
+		if rule != "" && strings.HasPrefix(p, rule) && len(rule) > match {
+			match, allowed = len(rule), false
+		}
+	}
+	for _, rule := range r.allow {
+		if strings.HasPrefix(p, rule) && len(rule) >= match {
+			match, allowed = len(rule), true
+		}
+	}
+	return allowed
+}
+
+func fetchRobots(base *url.URL) robotsRules {
+	var rules robotsRules
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s/robots.txt", base.Scheme, base.Host), nil)
+	if err != nil {
+		return rules
+	}
+	req.Header.Set("User-Agent", crawlUserAgent)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return rules
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return rules
+	}
+
+	applies, inAgents := false, false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if key == "user-agent" {
+			if !inAgents {
+				applies = false
+			}
+			inAgents = true
+			applies = applies || value == "*" || strings.EqualFold(value, crawlUserAgent)
+			continue
+		}
+		inAgents = false
+		if !applies {
+			continue
+		}
+		switch key {
+		case "allow":
+			rules.allow = append(rules.allow, value)
+		case "disallow":
+			rules.disallow = append(rules.disallow, value)
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+				rules.delay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+	return rules
+}
+
+func fetchPage(pageUrl string, headers map[string]string) (string, bool, error) {
+	req, err := http.NewRequest("GET", pageUrl, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("User-Agent", crawlUserAgent)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("failed to fetch URL: %s: %s", pageUrl, resp.Status)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return "", false, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	return string(body), true, err
+}
+
+func crawlAllowed(u *url.URL, source DocumentSource, domains []string) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	if !containsString(domains, u.Hostname()) {
+		return false
+	}
+	if source.Site {
+		seed, err := url.Parse(source.Source)
+		if err == nil && !strings.HasPrefix(u.Path, siteBasePath(seed)) {
+			return false
+		}
+	}
+	for _, exclude := range source.ExcludePaths {
+		if matched, _ := path.Match(exclude, u.Path); matched || strings.HasPrefix(u.Path, exclude) {
+			return false
+		}
+	}
+	return true
+}
+
+func handleCrawlSource(source DocumentSource) ([]sourceFile, string, error) {
+	seed, err := url.Parse(source.Source)
+	if err != nil || seed.Host == "" {
+		return nil, "", fmt.Errorf("invalid crawl source %s", source.Source)
+	}
+	maxDepth := source.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = 3
+	}
+	domains := source.AllowDomains
+	if len(domains) == 0 {
+		domains = []string{seed.Hostname()}
+	}
+	delay := time.Second
+	if source.Delay != "" {
+		if delay, err = time.ParseDuration(source.Delay); err != nil {
+			return nil, "", fmt.Errorf("invalid delay %s: %v", source.Delay, err)
+		}
+	}
+
+	headers, err := urlHeaders(source)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tempDir, err := os.MkdirTemp("", "oictl_crawl_")
+	if err != nil {
+		return nil, "", err
+	}
+
+	robots := make(map[string]robotsRules)
+	seed.Fragment = ""
+	seen := map[string]bool{seed.String(): true}
+	queue := []*url.URL{seed}
+	var files []sourceFile
+	for depth := 0; depth <= maxDepth && len(queue) > 0; depth++ {
+		var next []*url.URL
+		for _, page := range queue {
+			rules, ok := robots[page.Host]
+			if !ok {
+				rules = fetchRobots(page)
+				robots[page.Host] = rules
+			}
+			if !rules.allowed(page.EscapedPath()) {
+				continue
+			}
+			wait := delay
+			if rules.delay > wait {
+				wait = rules.delay
+			}
+			if len(files) > 0 {
+				time.Sleep(wait)
+			}
+
+			pageHeaders := headers
+			if page.Host != seed.Host {
+				pageHeaders = nil
+			}
+			content, isHtml, err := fetchPage(page.String(), pageHeaders)
+			if err != nil {
+				logf("Error fetching %s: %v\n", page, err)
+				continue
+			}
+			if !isHtml {
+				continue
+			}
+			name := pageFilename(page.String())
+			target := filepath.Join(tempDir, name)
+			if err := os.WriteFile(target, []byte(pageDocument(page.String(), content)), 0644); err != nil {
+				os.RemoveAll(tempDir)
+				return nil, "", err
+			}
+			files = append(files, sourceFile{Path: target, Filename: name})
+			logf("\rPages crawled: %d", len(files))
+
+			if depth == maxDepth {
+				continue
+			}
+			for _, match := range htmlLinkPattern.FindAllStringSubmatch(content, -1) {
+				link, err := page.Parse(strings.TrimSpace(match[1]))
+				if err != nil {
+					continue
+				}
+				link.Fragment = ""
+				if seen[link.String()] || !crawlAllowed(link, source, domains) {
+					continue
+				}
+				seen[link.String()] = true
+				next = append(next, link)
+			}
+		}
+		queue = next
+	}
+	if len(files) > 0 {
+		logf("\n")
+	}
+	return files, tempDir, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+)
+
+type contentIndex struct {
+	mu          sync.Mutex
+	collections map[string]string
+}
+
+var uploadedContent = &contentIndex{}
+
+func (i *contentIndex) add(hash, collection string) {
+	if hash == "" || collection == "" {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.collections == nil {
+		i.collections = make(map[string]string)
+	}
+	i.collections[hash] = collection
+}
+
+func (i *contentIndex) collection(hash string) (string, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	collection, ok := i.collections[hash]
+	return collection, ok
+}
+
+func fileSha256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func documentHashes(tag string) (map[string]uploadedDocument, map[string]string, error) {
+	documents, err := getDocs(TOKEN)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make(map[string]uploadedDocument)
+	names := make(map[string]string)
+	for _, doc := range documents {
+		uploadedContent.add(doc.Content.Sha256, doc.CollectionName)
+		for _, docTag := range doc.Content.Tags {
+			if docTag.Name != tag {
+				continue
+			}
+			if doc.Content.Sha256 != "" {
+				hashes[doc.Content.Sha256] = uploadedDocument{Collection: doc.CollectionName, Name: doc.Name}
+			}
+			names[doc.Name] = doc.Name
+			if doc.Filename != "" {
+				names[doc.Filename] = doc.Name
+			}
+			break
+		}
+	}
+	return hashes, names, nil
+}
+
+func resolveConflicts(files []sourceFile, entries []stateEntry, names map[string]string, onConflict string, replace map[string]string, state *syncState) ([]sourceFile, []stateEntry, []skippedFile) {
+	var pending []sourceFile
+	var pendingEntries []stateEntry
+	var skipped []skippedFile
+	for i, file := range files {
+		existing, ok := names[file.Filename]
+		if !ok {
+			pending = append(pending, file)
+			pendingEntries = append(pendingEntries, entries[i])
+			continue
+		}
+		if onConflict == "skip" {
+			state.markSeen(entries[i].key())
+			skipped = append(skipped, skippedFile{Name: file.Filename, Reason: "document " + existing + " already exists"})
+			continue
+		}
+		replace[file.Filename] = existing
+		delete(names, file.Filename)
+		pending = append(pending, file)
+		pendingEntries = append(pendingEntries, entries[i])
+	}
+	return pending, pendingEntries, skipped
+}
+
+func pendingFiles(files []sourceFile, tag, source string, hashes map[string]uploadedDocument, state *syncState, force bool) ([]sourceFile, []stateEntry, int) {
+	var pending []sourceFile
+	var entries []stateEntry
+	unchanged := 0
+	for _, file := range files {
+		entry, err := fileEntry(file, tag, source)
+		previous, known := state.find(entry.key())
+		if err == nil && hashes != nil && !force && known && previous.ModTime.Equal(entry.ModTime) && previous.Size == entry.Size {
+			if _, ok := hashes[previous.Sha256]; ok {
+				state.markSeen(entry.key())
+				unchanged++
+				continue
+			}
+		}
+		if err == nil {
+			entry.Sha256, err = fileSha256(file.Path)
+		}
+		if err != nil || hashes == nil || force {
+			pending = append(pending, file)
+			entries = append(entries, entry)
+			continue
+		}
+
+		if doc, ok := hashes[entry.Sha256]; ok {
+			if known && previous.Sha256 == entry.Sha256 {
+				doc = uploadedDocument{Collection: previous.Collection, Name: previous.Document}
+			}
+			entry.Collection, entry.Document = doc.Collection, doc.Name
+			state.record(entry)
+			unchanged++
+			continue
+		}
+		hashes[entry.Sha256] = uploadedDocument{}
+		pending = append(pending, file)
+		entries = append(entries, entry)
+	}
+	return pending, entries, unchanged
+}
+
+func pruneDocuments(state *syncState, removed []stateEntry, report *applyReport) {
+	for _, entry := range removed {
+		if entry.Document != "" && !state.inUse(entry.Document) {
+			deleteUrl := fmt.Sprintf("%s/api/v1/documents/doc/delete?name=%s", BASE_URL, url.QueryEscape(entry.Document))
+			if err := apiRequest("DELETE", deleteUrl, nil, nil); err != nil {
+				logf("Error deleting document %s: %v\n", entry.Document, err)
+				report.failed("Document", entry.Document, err)
+				continue
+			}
+			logf("Document %s of %s pruned\n", entry.Document, entry.displayName())
+			report.Pruned++
+		}
+		state.forget(entry)
+	}
+}
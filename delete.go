@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+)
+
+func deleteModel(config Model) error {
+	deleteUrl := fmt.Sprintf("%s/api/v1/models/delete?id=%s", BASE_URL, url.QueryEscape(config.Metadata.Name))
+	return apiRequest("DELETE", deleteUrl, nil, nil)
+}
+
+func deleteDocuments(config Documents) (int, error) {
+	documents, err := getDocs(TOKEN)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, doc := range documents {
+		for _, docTag := range doc.Content.Tags {
+			if docTag.Name != config.Metadata.Name {
+				continue
+			}
+			deleteUrl := fmt.Sprintf("%s/api/v1/documents/doc/delete?name=%s", BASE_URL, url.QueryEscape(doc.Name))
+			if err := apiRequest("DELETE", deleteUrl, nil, nil); err != nil {
+				logf("Error deleting document %s: %v\n", doc.Name, err)
+				break
+			}
+			deleted++
+			break
+		}
+	}
+	return deleted, nil
+}
+
+func runDelete(args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	file := fs.String("f", "", "path to a definition file or directory")
+	mf := addManifestFlags(fs)
+	fs.Parse(args)
+
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	opts, err := mf.options()
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("no definition path given, use -f")
+	}
+
+	paths, err := collectPaths(*file, &opts)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range loadManifests(paths, opts) {
+		switch c := m.Config.(type) {
+		case Webhook:
+			continue
+		case Documents:
+			deleted, err := deleteDocuments(c)
+			if err != nil {
+				logf("Error deleting documents %s: %v\n", m.Path, err)
+				continue
+			}
+			logf("Documents %s deleted (%d documents)\n", c.Metadata.Name, deleted)
+		case Model:
+			if err := deleteModel(c); err != nil {
+				logf("Error deleting model %s: %v\n", m.Path, err)
+				continue
+			}
+			logf("Model %s deleted\n", c.Metadata.Name)
+		default:
+			if err := deleteResource(m); err != nil {
+				logf("Error deleting %s %s: %v\n", m.Kind, m.Path, err)
+				continue
+			}
+			logf("%s %s deleted\n", m.Kind, m.Metadata.Name)
+		}
+	}
+	return nil
+}
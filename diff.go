@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type inventoryDiff struct {
+	onlyFrom []string
+	onlyTo   []string
+	differ   map[string][]string
+}
+
+func flattenValue(prefix string, value interface{}, out map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			flattenValue(strings.TrimPrefix(prefix+"."+key, "."), item, out)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flattenValue(fmt.Sprintf("%s[%d]", prefix, i), item, out)
+		}
+	default:
+		out[prefix] = fmt.Sprint(v)
+	}
+}
+
+func flattenResource(resource interface{}) map[string]string {
+	content, _ := yaml.Marshal(resource)
+	var generic interface{}
+	yaml.Unmarshal(content, &generic)
+	out := make(map[string]string)
+	flattenValue("", generic, out)
+	return out
+}
+
+func differingFields(a, b map[string]string) []string {
+	fields := make(map[string]bool)
+	for key, value := range a {
+		if b[key] != value {
+			fields[key] = true
+		}
+	}
+	for key, value := range b {
+		if a[key] != value {
+			fields[key] = true
+		}
+	}
+	var sorted []string
+	for field := range fields {
+		sorted = append(sorted, field)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func diffInventories(from, to map[string]map[string]string) inventoryDiff {
+	diff := inventoryDiff{differ: make(map[string][]string)}
+	for name, fields := range from {
+		other, ok := to[name]
+		if !ok {
+			diff.onlyFrom = append(diff.onlyFrom, name)
+			continue
+		}
+		if fields := differingFields(fields, other); len(fields) > 0 {
+			diff.differ[name] = fields
+		}
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			diff.onlyTo = append(diff.onlyTo, name)
+		}
+	}
+	sort.Strings(diff.onlyFrom)
+	sort.Strings(diff.onlyTo)
+	return diff
+}
+
+func documentInventory(documents []Document) map[string]map[string]string {
+	inventory := make(map[string]map[string]string)
+	for _, doc := range documents {
+		var tags []string
+		for _, tag := range doc.Content.Tags {
+			tags = append(tags, tag.Name)
+		}
+		sort.Strings(tags)
+		inventory[doc.Name] = flattenResource(map[string]interface{}{
+			"filename":    doc.Filename,
+			"tags":        tags,
+			"labels":      doc.Content.Labels,
+			"annotations": doc.Content.Annotations,
+		})
+	}
+	return inventory
+}
+
+func modelInventory(models []Model) map[string]map[string]string {
+	inventory := make(map[string]map[string]string)
+	for _, model := range models {
+		inventory[model.Metadata.Name] = flattenResource(model)
+	}
+	return inventory
+}
+
+func (d inventoryDiff) print(kind, from, to string) int {
+	logf("%s:\n", kind)
+	for _, name := range d.onlyFrom {
+		logf("  - %s (only in %s)\n", name, from)
+	}
+	for _, name := range d.onlyTo {
+		logf("  + %s (only in %s)\n", name, to)
+	}
+	var names []string
+	for name := range d.differ {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		logf("  ~ %s (differs: %s)\n", name, strings.Join(d.differ[name], ", "))
+	}
+	count := len(d.onlyFrom) + len(d.onlyTo) + len(d.differ)
+	if count == 0 {
+		logf("  no differences\n")
+	}
+	return count
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fromName := fs.String("from", "", "context name or server URL to compare from")
+	toName := fs.String("to", "", "context name or server URL to compare to")
+	fs.Parse(args)
+
+	if *fromName == "" || *toName == "" {
+		return fmt.Errorf("usage: oictl diff --from <context> --to <context>")
+	}
+	from, err := findContext(*fromName)
+	if err != nil {
+		return err
+	}
+	to, err := findContext(*toName)
+	if err != nil {
+		return err
+	}
+
+	var fromInventory, toInventory serverInventory
+	if err := withContext(from, func() error {
+		fromInventory, err = readInventory()
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to read %s: %v", from.Name, err)
+	}
+	if err := withContext(to, func() error {
+		toInventory, err = readInventory()
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to read %s: %v", to.Name, err)
+	}
+
+	differences := diffInventories(modelInventory(fromInventory.models), modelInventory(toInventory.models)).print("Models", from.Name, to.Name)
+	differences += diffInventories(documentInventory(fromInventory.documents), documentInventory(toInventory.documents)).print("Documents", from.Name, to.Name)
+	if differences > 0 {
+		return fmt.Errorf("%d differences between %s and %s", differences, from.Name, to.Name)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type discoursePost struct {
+	PostNumber     int    `json:"post_number"`
+	Username       string `json:"username"`
+	Cooked         string `json:"cooked"`
+	CreatedAt      string `json:"created_at"`
+	AcceptedAnswer bool   `json:"accepted_answer"`
+}
+
+type discourseTopic struct {
+	ID             int           `json:"id"`
+	Title          string        `json:"title"`
+	Slug           string        `json:"slug"`
+	Tags           []interface{} `json:"tags"`
+	AcceptedAnswer *struct {
+		PostNumber int `json:"post_number"`
+	} `json:"accepted_answer"`
+	PostStream struct {
+		Posts []discoursePost `json:"posts"`
+	} `json:"post_stream"`
+}
+
+func discourseGet(source DocumentSource, endpoint string, out interface{}) error {
+	rawUrl := strings.TrimSuffix(source.Source, "/") + endpoint
+	req, err := http.NewRequest("GET", rawUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if source.Token != "" {
+		req.Header.Set("Api-Key", source.Token)
+		username := source.Username
+		if username == "" {
+			username = "system"
+		}
+		req.Header.Set("Api-Username", username)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GET %s: %s - %s", rawUrl, resp.Status, string(respBody))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func discourseTopicIDs(source DocumentSource, listEndpoint string) ([]int, error) {
+	var ids []int
+	for page := 0; ; page++ {
+		var list struct {
+			TopicList struct {
+				Topics []struct {
+					ID int `json:"id"`
+				} `json:"topics"`
+				MoreTopicsUrl string `json:"more_topics_url"`
+			} `json:"topic_list"`
+		}
+		if err := discourseGet(source, fmt.Sprintf("%s?page=%d", listEndpoint, page), &list); err != nil {
+			return nil, err
+		}
+		for _, topic := range list.TopicList.Topics {
+			ids = append(ids, topic.ID)
+		}
+		if len(list.TopicList.Topics) == 0 || list.TopicList.MoreTopicsUrl == "" {
+			return ids, nil
+		}
+	}
+}
+
+func discourseTopicDocument(baseUrl string, topic discourseTopic) string {
+	accepted := 0
+	if topic.AcceptedAnswer != nil {
+		accepted = topic.AcceptedAnswer.PostNumber
+	}
+	for _, post := range topic.PostStream.Posts {
+		if post.AcceptedAnswer {
+			accepted = post.PostNumber
+		}
+	}
+
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "# %s\n\n", topic.Title)
+	fmt.Fprintf(&doc, "- Link: %s/t/%s/%d\n", strings.TrimSuffix(baseUrl, "/"), topic.Slug, topic.ID)
+	if tags := discourseTags(topic); len(tags) > 0 {
+		fmt.Fprintf(&doc, "- Tags: %s\n", strings.Join(tags, ", "))
+	}
+	for _, post := range topic.PostStream.Posts {
+		if post.PostNumber == 1 {
+			_, text := htmlToMarkdown(post.Cooked, "")
+			fmt.Fprintf(&doc, "- Author: %s\n- Created: %s\n\n%s\n", post.Username, post.CreatedAt, text)
+		}
+	}
+	for _, post := range topic.PostStream.Posts {
+		if post.PostNumber == accepted {
+			_, text := htmlToMarkdown(post.Cooked, "")
+			fmt.Fprintf(&doc, "\n## Accepted answer (%s)\n\n%s\n", post.Username, text)
+		}
+	}
+	replies := false
+	for _, post := range topic.PostStream.Posts {
+		if post.PostNumber == 1 || post.PostNumber == accepted {
+			continue
+		}
+		if !replies {
+			doc.WriteString("\n## Replies\n")
+			replies = true
+		}
+		_, text := htmlToMarkdown(post.Cooked, "")
+		fmt.Fprintf(&doc, "\n### %s\n\n%s\n", post.Username, text)
+	}
+	return doc.String()
+}
+
+func discourseTags(topic discourseTopic) []string {
+	var tags []string
+	for _, tag := range topic.Tags {
+		switch t := tag.(type) {
+		case string:
+			tags = append(tags, t)
+		case map[string]interface{}:
+			if name, ok := t["name"].(string); ok {
+				tags = append(tags, name)
+			}
+		}
+	}
+	return tags
+}
+
+func handleDiscourseSource(source DocumentSource) ([]sourceFile, string, error) {
+	registerSecret(source.Token)
+	endpoints := []string{"/latest.json"}
+	if len(source.Categories) > 0 {
+		endpoints = nil
+		for _, category := range source.Categories {
+			endpoints = append(endpoints, fmt.Sprintf("/c/%d.json", category))
+		}
+	}
+
+	var ids []int
+	seen := make(map[int]bool)
+	for _, endpoint := range endpoints {
+		topicIDs, err := discourseTopicIDs(source, endpoint)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, id := range topicIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "oictl_discourse_")
+	if err != nil {
+		return nil, "", err
+	}
+	var files []sourceFile
+	for _, id := range ids {
+		var topic discourseTopic
+		if err := discourseGet(source, fmt.Sprintf("/t/%d.json", id), &topic); err != nil {
+			logf("Error fetching topic %d: %v\n", id, err)
+			continue
+		}
+		name := fmt.Sprintf("topic-%d-%s.md", topic.ID, safeArchiveName(topic.Slug))
+		target := filepath.Join(tempDir, name)
+		if err := os.WriteFile(target, []byte(discourseTopicDocument(source.Source, topic)), 0644); err != nil {
+			os.RemoveAll(tempDir)
+			return nil, "", err
+		}
+		files = append(files, sourceFile{Path: target, Filename: name, Tags: discourseTags(topic)})
+		logf("\rDiscourse topics fetched: %d/%d", len(files), len(ids))
+	}
+	if len(files) > 0 {
+		logf("\n")
+	}
+	return files, tempDir, nil
+}
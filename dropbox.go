@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type dropboxEntry struct {
+	Tag         string `json:".tag"`
+	ID          string `json:"id"`
+	PathLower   string `json:"path_lower"`
+	PathDisplay string `json:"path_display"`
+}
+
+type dropboxListing struct {
+	Entries []dropboxEntry `json:"entries"`
+	Cursor  string         `json:"cursor"`
+	HasMore bool           `json:"has_more"`
+}
+
+func dropboxRequest(token, endpoint string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", "https://api.dropboxapi.com/2"+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("dropbox %s: %s - %s", endpoint, resp.Status, string(respBody))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func dropboxCursorPath() string {
+	return filepath.Join(filepath.Dir(configPath()), "dropbox-cursors.json")
+}
+
+func loadDropboxCursors() map[string]string {
+	cursors := make(map[string]string)
+	if content, err := os.ReadFile(dropboxCursorPath()); err == nil {
+		json.Unmarshal(content, &cursors)
+	}
+	return cursors
+}
+
+func saveDropboxCursor(key, cursor string) error {
+	cursors := loadDropboxCursors()
+	cursors[key] = cursor
+	content, err := json.MarshalIndent(cursors, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dropboxCursorPath()), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(dropboxCursorPath(), content, 0600)
+}
+
+func listDropboxFolder(token, folder, cursor string) ([]dropboxEntry, string, error) {
+	var listing dropboxListing
+	var err error
+	if cursor != "" {
+		err = dropboxRequest(token, "/files/list_folder/continue", map[string]interface{}{"cursor": cursor}, &listing)
+	} else {
+		err = dropboxRequest(token, "/files/list_folder", map[string]interface{}{"path": folder, "recursive": true}, &listing)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries := listing.Entries
+	for listing.HasMore {
+		cursor := listing.Cursor
+		listing = dropboxListing{}
+		if err := dropboxRequest(token, "/files/list_folder/continue", map[string]interface{}{"cursor": cursor}, &listing); err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, listing.Entries...)
+	}
+	return entries, listing.Cursor, nil
+}
+
+func downloadDropboxFile(token, id, target string) error {
+	arg, _ := json.Marshal(map[string]string{"path": id})
+	req, err := http.NewRequest("POST", "https://content.dropboxapi.com/2/files/download", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Dropbox-API-Arg", string(arg))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("dropbox download %s: %s - %s", id, resp.Status, string(respBody))
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
+
+func handleDropboxSource(source DocumentSource) ([]string, string, func(), error) {
+	if source.Token == "" {
+		return nil, "", nil, fmt.Errorf("dropbox source %s needs a token", source.Source)
+	}
+	registerSecret(source.Token)
+
+	root := "/" + strings.Trim(source.Source, "/")
+	folders := []string{root}
+	if len(source.Dir) > 0 {
+		folders = nil
+		for _, dir := range source.Dir {
+			folders = append(folders, path.Join(root, dir))
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "oictl_dropbox_")
+	if err != nil {
+		return nil, "", nil, err
+	}
+	cursors := loadDropboxCursors()
+	newCursors := make(map[string]string)
+	var files []string
+	for _, folder := range folders {
+		key := BASE_URL + " " + strings.ToLower(folder)
+		cursor := ""
+		if source.Incremental {
+			cursor = cursors[key]
+		}
+		listFolder := folder
+		if listFolder == "/" {
+			listFolder = ""
+		}
+		entries, next, err := listDropboxFolder(source.Token, listFolder, cursor)
+		if err != nil {
+			os.RemoveAll(tempDir)
+			return nil, "", nil, err
+		}
+		newCursors[key] = next
+
+		for _, entry := range entries {
+			if entry.Tag != "file" || (len(source.Extensions) > 0 && !hasExtension(entry.PathLower, source.Extensions)) {
+				continue
+			}
+			relative := strings.TrimPrefix(entry.PathDisplay, root)
+			target := filepath.Join(tempDir, filepath.FromSlash(path.Clean("/"+relative)))
+			if err := downloadDropboxFile(source.Token, entry.ID, target); err != nil {
+				os.RemoveAll(tempDir)
+				return nil, "", nil, err
+			}
+			files = append(files, target)
+		}
+		if cursor != "" {
+			logf("Dropbox %s: %d changed entries since the last sync\n", folder, len(entries))
+		}
+	}
+
+	commit := func() {
+		if !source.Incremental {
+			return
+		}
+		for key, cursor := range newCursors {
+			if err := saveDropboxCursor(key, cursor); err != nil {
+				logf("Failed to save Dropbox cursor: %v\n", err)
+			}
+		}
+	}
+	return files, tempDir, commit, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+func normalizeEncoding(name string) string {
+	name = strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(name))
+	switch name {
+	case "utf8":
+		return "utf-8"
+	case "utf16", "utf16le", "utf16be":
+		return "utf-16" + strings.TrimPrefix(name, "utf16")
+	case "latin1", "iso88591", "l1":
+		return "latin-1"
+	case "windows1252", "cp1252":
+		return "windows-1252"
+	}
+	return ""
+}
+
+func utf16Order(data []byte) string {
+	if len(data) > 1024 {
+		data = data[:1024]
+	}
+	if len(data) < 4 {
+		return ""
+	}
+	pairs := len(data) / 2
+	evenZeros, oddZeros := 0, 0
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	switch {
+	case oddZeros*10 > pairs*4 && evenZeros*20 < pairs:
+		return "utf-16le"
+	case evenZeros*10 > pairs*4 && oddZeros*20 < pairs:
+		return "utf-16be"
+	}
+	return ""
+}
+
+func detectEncoding(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return "utf-8"
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return "utf-16le"
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return "utf-16be"
+	}
+	if order := utf16Order(data); order != "" {
+		return order
+	}
+	if utf8.Valid(data) {
+		return "utf-8"
+	}
+	return ""
+}
+
+func decodeText(data []byte, encoding string) string {
+	switch encoding {
+	case "utf-16", "utf-16le", "utf-16be":
+		bigEndian := encoding == "utf-16be"
+		if bytes.HasPrefix(data, []byte{0xfe, 0xff}) {
+			bigEndian = true
+		} else if bytes.HasPrefix(data, []byte{0xff, 0xfe}) {
+			bigEndian = false
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+			} else {
+				units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+			}
+		}
+		return strings.TrimPrefix(string(utf16.Decode(units)), "\ufeff")
+	case "latin-1", "windows-1252":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+			if encoding == "windows-1252" && b >= 0x80 && b < 0xa0 {
+				runes[i] = windows1252[b-0x80]
+			}
+		}
+		return string(runes)
+	}
+	return strings.TrimPrefix(string(data), "\ufeff")
+}
+
+func transcodeSourceFiles(source DocumentSource, files []sourceFile) ([]sourceFile, []skippedFile, func(), error) {
+	cleanup := func() {}
+	encoding := ""
+	if source.Encoding != "" {
+		if encoding = normalizeEncoding(source.Encoding); encoding == "" {
+			return nil, nil, cleanup, fmt.Errorf("unsupported encoding %q, expected utf-8, utf-16, utf-16le, utf-16be, latin-1 or windows-1252", source.Encoding)
+		}
+		if encoding == "utf-8" {
+			return files, nil, cleanup, nil
+		}
+	}
+
+	var tempDir string
+	var kept []sourceFile
+	var skipped []skippedFile
+	for i, file := range files {
+		if !containsString(textExtensions, strings.ToLower(filepath.Ext(file.Filename))) {
+			kept = append(kept, file)
+			continue
+		}
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			return nil, nil, cleanup, err
+		}
+		fileEncoding := encoding
+		if fileEncoding == "" {
+			fileEncoding = detectEncoding(data)
+		}
+		if fileEncoding == "" && source.SkipUnknownEncoding {
+			skipped = append(skipped, skippedFile{Name: file.Filename, Reason: "unknown character encoding", Origin: file.Origin, Root: file.Root})
+			continue
+		}
+		if fileEncoding == "" {
+			fileEncoding = "windows-1252"
+		}
+		if fileEncoding == "utf-8" && !bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}) {
+			kept = append(kept, file)
+			continue
+		}
+
+		if tempDir == "" {
+			if tempDir, err = os.MkdirTemp("", "oictl_encoding_"); err != nil {
+				return nil, nil, cleanup, err
+			}
+			cleanup = func() { os.RemoveAll(tempDir) }
+		}
+		target := filepath.Join(tempDir, strconv.Itoa(i), filepath.Base(file.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, nil, cleanup, err
+		}
+		if err := os.WriteFile(target, []byte(decodeText(data, fileEncoding)), 0644); err != nil {
+			return nil, nil, cleanup, err
+		}
+		debugf("Converted %s from %s to UTF-8\n", file.Filename, fileEncoding)
+		file.Path = target
+		kept = append(kept, file)
+	}
+	return kept, skipped, cleanup, nil
+}
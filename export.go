@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ServerAccessGrant struct {
+	GroupIDs []string `json:"group_ids"`
+	UserIDs  []string `json:"user_ids"`
+}
+
+type ServerModel struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	BaseModelID string `json:"base_model_id"`
+	IsActive    *bool  `json:"is_active"`
+	Meta        struct {
+		ProfileImageURL   string             `json:"profile_image_url"`
+		Description       string             `json:"description"`
+		Capabilities      map[string]bool    `json:"capabilities"`
+		SuggestionPrompts []SuggestionPrompt `json:"suggestion_prompts"`
+		Tags              []struct {
+			Name string `json:"name"`
+		} `json:"tags"`
+		Knowledge []struct {
+			ID              string   `json:"id"`
+			Name            string   `json:"name"`
+			Type            string   `json:"type"`
+			CollectionNames []string `json:"collection_names"`
+		} `json:"knowledge"`
+		ToolIDs     []string          `json:"toolIds"`
+		FilterIDs   []string          `json:"filterIds"`
+		ActionIDs   []string          `json:"actionIds"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"meta"`
+	Params        map[string]interface{} `json:"params"`
+	AccessControl *struct {
+		Read  ServerAccessGrant `json:"read"`
+		Write ServerAccessGrant `json:"write"`
+	} `json:"access_control"`
+}
+
+func getModel(id string) (ServerModel, error) {
+	var model ServerModel
+	err := apiRequest("GET", fmt.Sprintf("%s/api/v1/models/model?id=%s", BASE_URL, url.QueryEscape(id)), nil, &model)
+	return model, err
+}
+
+func boolPtr(capabilities map[string]bool, name string) *bool {
+	if enabled, ok := capabilities[name]; ok {
+		return &enabled
+	}
+	return nil
+}
+
+func exportAccessGrant(grant ServerAccessGrant, groups []ServerGroup, users []ServerUser) AccessGrant {
+	var exported AccessGrant
+	for _, id := range grant.GroupIDs {
+		ref := id
+		for _, group := range groups {
+			if group.ID == id {
+				ref = group.Name
+			}
+		}
+		exported.Groups = append(exported.Groups, ref)
+	}
+	for _, id := range grant.UserIDs {
+		ref := id
+		for _, user := range users {
+			if user.ID == id {
+				ref = user.Email
+			}
+		}
+		exported.Users = append(exported.Users, ref)
+	}
+	return exported
+}
+
+func promptToManifest(item map[string]interface{}) Prompt {
+	var prompt Prompt
+	prompt.Kind = "Prompt"
+	command, _ := item["command"].(string)
+	prompt.Metadata.Name = strings.TrimPrefix(command, "/")
+	prompt.Spec.Title, _ = item["title"].(string)
+	prompt.Spec.Content, _ = item["content"].(string)
+	return prompt
+}
+
+func toolToManifest(item map[string]interface{}) Tool {
+	var tool Tool
+	tool.Kind = "Tool"
+	tool.Metadata.Name, _ = item["id"].(string)
+	if name, _ := item["name"].(string); name != tool.Metadata.Name {
+		tool.Spec.Name = name
+	}
+	tool.Spec.Content, _ = item["content"].(string)
+	if meta, ok := item["meta"].(map[string]interface{}); ok {
+		tool.Spec.Description, _ = meta["description"].(string)
+	}
+	return tool
+}
+
+func documentArchivePath(doc Document) string {
+	return path.Join("files", safeArchiveName(doc.Name), safeArchiveName(doc.Filename))
+}
+
+func documentsToManifests(documents []Document) []Documents {
+	var manifests []Documents
+	groups := make(map[string]int)
+	for _, doc := range documents {
+		var tags []string
+		for _, tag := range doc.Content.Tags {
+			tags = append(tags, tag.Name)
+		}
+		name := "untagged"
+		if len(tags) > 0 {
+			name, tags = tags[0], tags[1:]
+		}
+		labels, _ := json.Marshal(doc.Content.Labels)
+		annotations, _ := json.Marshal(doc.Content.Annotations)
+		key := name + "\x00" + string(labels) + "\x00" + string(annotations)
+		i, ok := groups[key]
+		if !ok {
+			var manifest Documents
+			manifest.Kind = "Documents"
+			manifest.Metadata.Name = name
+			manifest.Metadata.Labels = doc.Content.Labels
+			manifest.Metadata.Annotations = doc.Content.Annotations
+			i = len(manifests)
+			groups[key] = i
+			manifests = append(manifests, manifest)
+		}
+		manifests[i].Spec.Sources = append(manifests[i].Spec.Sources, DocumentSource{Source: "../" + documentArchivePath(doc), Tags: tags})
+	}
+	return manifests
+}
+
+func accessLookups() ([]ServerGroup, []ServerUser, error) {
+	groups, err := getGroups()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list groups: %v", err)
+	}
+	users, err := getUsers()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list users: %v", err)
+	}
+	return groups, users, nil
+}
+
+func modelToManifest(server ServerModel, groups []ServerGroup, users []ServerUser) Model {
+	var model Model
+	model.Kind = "Model"
+	model.Metadata.Name = server.ID
+	model.Metadata.Labels = server.Meta.Labels
+	model.Metadata.Annotations = server.Meta.Annotations
+	if server.Name != server.ID {
+		model.Spec.Name = server.Name
+	}
+	model.Spec.BaseModelID = server.BaseModelID
+	model.Spec.IsActive = server.IsActive
+
+	meta := &model.Spec.Meta
+	meta.ProfileImageURL = server.Meta.ProfileImageURL
+	meta.Description = server.Meta.Description
+	meta.Capabilities.Vision = server.Meta.Capabilities["vision"]
+	meta.Capabilities.WebSearch = boolPtr(server.Meta.Capabilities, "web_search")
+	meta.Capabilities.ImageGeneration = boolPtr(server.Meta.Capabilities, "image_generation")
+	meta.Capabilities.CodeInterpreter = boolPtr(server.Meta.Capabilities, "code_interpreter")
+	meta.Capabilities.Citations = boolPtr(server.Meta.Capabilities, "citations")
+	meta.SuggestionPrompts = server.Meta.SuggestionPrompts
+	meta.ToolIDs = server.Meta.ToolIDs
+	meta.FilterIDs = server.Meta.FilterIDs
+	meta.ActionIDs = server.Meta.ActionIDs
+	for _, tag := range server.Meta.Tags {
+		meta.Tags = append(meta.Tags, tag.Name)
+	}
+	for _, knowledge := range server.Meta.Knowledge {
+		if knowledge.ID != "" && len(knowledge.CollectionNames) == 0 {
+			meta.Knowledge = append(meta.Knowledge, ModelKnowledge{Knowledge: knowledge.Name})
+			continue
+		}
+		meta.Knowledge = append(meta.Knowledge, ModelKnowledge{Tags: knowledge.Name})
+	}
+
+	var paramNames []string
+	for name := range server.Params {
+		paramNames = append(paramNames, name)
+	}
+	sort.Strings(paramNames)
+	for _, name := range paramNames {
+		value := server.Params[name]
+		if name == "system" {
+			model.Spec.SystemPrompt = fmt.Sprint(value)
+			continue
+		}
+		if model.Spec.Params == nil {
+			model.Spec.Params = make(map[string]interface{})
+		}
+		model.Spec.Params[name] = value
+	}
+
+	if server.AccessControl == nil {
+		model.Spec.AccessControl = &AccessControl{Public: true}
+	} else {
+		model.Spec.AccessControl = &AccessControl{
+			Read:  exportAccessGrant(server.AccessControl.Read, groups, users),
+			Write: exportAccessGrant(server.AccessControl.Write, groups, users),
+		}
+	}
+	return model
+}
+
+func marshalManifest(manifest interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	encoder.Close()
+	return buf.Bytes(), nil
+}
+
+func writeManifest(output string, manifest interface{}) error {
+	content, err := marshalManifest(manifest)
+	if err != nil {
+		return err
+	}
+	if output == "" || output == "-" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return os.WriteFile(output, content, 0644)
+}
+
+func exportFeedback(output string) error {
+	feedbacks, err := getRawList("/api/v1/evaluations/feedbacks/all")
+	if err != nil {
+		return err
+	}
+
+	var content bytes.Buffer
+	for _, feedback := range feedbacks {
+		if err := json.Compact(&content, feedback); err != nil {
+			return err
+		}
+		content.WriteByte('\n')
+	}
+	if output == "" || output == "-" {
+		_, err = os.Stdout.Write(content.Bytes())
+		return err
+	}
+	if err := os.WriteFile(output, content.Bytes(), 0644); err != nil {
+		return err
+	}
+	logf("Exported %d feedback entries to %s\n", len(feedbacks), output)
+	return nil
+}
+
+type leaderboardEntry struct {
+	Model  string
+	Rating float64
+	Won    int
+	Lost   int
+}
+
+func buildLeaderboard(feedbacks []json.RawMessage) []leaderboardEntry {
+	entries := make(map[string]*leaderboardEntry)
+	entry := func(model string) *leaderboardEntry {
+		if entries[model] == nil {
+			entries[model] = &leaderboardEntry{Model: model, Rating: 1000}
+		}
+		return entries[model]
+	}
+
+	for _, raw := range feedbacks {
+		var feedback struct {
+			Data struct {
+				Rating          interface{} `json:"rating"`
+				ModelID         string      `json:"model_id"`
+				SiblingModelIDs []string    `json:"sibling_model_ids"`
+			} `json:"data"`
+		}
+		if json.Unmarshal(raw, &feedback) != nil || feedback.Data.ModelID == "" {
+			continue
+		}
+		rating, _ := strconv.Atoi(fmt.Sprint(feedback.Data.Rating))
+		if rating == 0 {
+			continue
+		}
+		model := entry(feedback.Data.ModelID)
+		for _, siblingID := range feedback.Data.SiblingModelIDs {
+			sibling := entry(siblingID)
+			outcome := 1.0
+			if rating < 0 {
+				outcome = 0
+			}
+			expected := 1 / (1 + math.Pow(10, (sibling.Rating-model.Rating)/400))
+			model.Rating += 32 * (outcome - expected)
+			sibling.Rating += 32 * (expected - outcome)
+			if outcome == 1 {
+				model.Won++
+				sibling.Lost++
+			} else {
+				model.Lost++
+				sibling.Won++
+			}
+		}
+	}
+
+	var leaderboard []leaderboardEntry
+	for _, e := range entries {
+		leaderboard = append(leaderboard, *e)
+	}
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Rating != leaderboard[j].Rating {
+			return leaderboard[i].Rating > leaderboard[j].Rating
+		}
+		return leaderboard[i].Model < leaderboard[j].Model
+	})
+	return leaderboard
+}
+
+func exportLeaderboard(output string) error {
+	feedbacks, err := getRawList("/api/v1/evaluations/feedbacks/all")
+	if err != nil {
+		return err
+	}
+
+	var content bytes.Buffer
+	writer := csv.NewWriter(&content)
+	writer.Write([]string{"rank", "model", "rating", "won", "lost"})
+	for i, e := range buildLeaderboard(feedbacks) {
+		writer.Write([]string{strconv.Itoa(i + 1), e.Model, fmt.Sprintf("%.0f", e.Rating), strconv.Itoa(e.Won), strconv.Itoa(e.Lost)})
+	}
+	writer.Flush()
+	if output == "" || output == "-" {
+		_, err = os.Stdout.Write(content.Bytes())
+		return err
+	}
+	return os.WriteFile(output, content.Bytes(), 0644)
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	output := fs.String("o", "", "file to write the manifest to (default stdout)")
+	positional := parseArgs(fs, args)
+
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("usage: oictl export model <name> | feedback | leaderboard [-o file]")
+	}
+
+	switch positional[0] {
+	case "model":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: oictl export model <name> [-o file]")
+		}
+		server, err := getModel(positional[1])
+		if err != nil {
+			return err
+		}
+		groups, users, err := accessLookups()
+		if err != nil {
+			return err
+		}
+		return writeManifest(*output, modelToManifest(server, groups, users))
+	case "feedback":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: oictl export feedback [-o feedback.jsonl]")
+		}
+		return exportFeedback(*output)
+	case "leaderboard":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: oictl export leaderboard [-o leaderboard.csv]")
+		}
+		return exportLeaderboard(*output)
+	default:
+		return fmt.Errorf("unknown export target %s", positional[0])
+	}
+}
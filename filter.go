@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var textExtensions = []string{".md", ".markdown", ".mdx", ".txt", ".rst", ".adoc", ".org", ".tex", ".csv", ".tsv", ".html", ".htm", ".xml", ".json", ".yaml", ".yml"}
+
+type skippedFile struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+	Origin string `json:"-"`
+	Root   string `json:"-"`
+	Err    error  `json:"-"`
+}
+
+func parseFileSize(size string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		factor int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid file size %q, expected a size like 512KB or 10MB", size)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+func sniffContentType(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if contentType == "application/octet-stream" && utf16Order(head[:n]) != "" {
+		contentType = "text/plain"
+	}
+	return contentType, nil
+}
+
+func matchesMimeType(contentType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToLower(pattern), contentType); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func checkContentType(source DocumentSource, file sourceFile) (string, error) {
+	sniffed, err := sniffContentType(file.Path)
+	if err != nil {
+		return "", err
+	}
+	if len(source.MimeTypes) > 0 && matchesMimeType(sniffed, source.MimeTypes) {
+		return "", nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	binary := containsString(textExtensions, ext) && !strings.HasPrefix(sniffed, "text/")
+	if len(source.MimeTypes) == 0 {
+		if binary {
+			return fmt.Sprintf("binary content (%s) in a %s file", sniffed, ext), nil
+		}
+		return "", nil
+	}
+	extType, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+	if !binary && extType != "" && matchesMimeType(extType, source.MimeTypes) {
+		return "", nil
+	}
+	return fmt.Sprintf("content type %s is not in mimeTypes", sniffed), nil
+}
+
+func filterSourceFiles(source DocumentSource, files []sourceFile) ([]sourceFile, []skippedFile, error) {
+	var maxSize int64
+	if source.MaxFileSize != "" {
+		var err error
+		if maxSize, err = parseFileSize(source.MaxFileSize); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var kept []sourceFile
+	var skipped []skippedFile
+	for _, file := range files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			kept = append(kept, file)
+			continue
+		}
+		if maxSize > 0 && info.Size() > maxSize {
+			skipped = append(skipped, skippedFile{Name: file.Filename, Reason: fmt.Sprintf("size %d bytes exceeds maxFileSize %s", info.Size(), source.MaxFileSize), Origin: file.Origin, Root: file.Root})
+			continue
+		}
+		reason, err := checkContentType(source, file)
+		if err != nil {
+			return nil, nil, err
+		}
+		if reason != "" {
+			skipped = append(skipped, skippedFile{Name: file.Filename, Reason: reason, Origin: file.Origin, Root: file.Root})
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, skipped, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import "fmt"
+
+type Folder struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Name         string   `yaml:"name,omitempty"`
+		Parent       string   `yaml:"parent,omitempty"`
+		Models       []string `yaml:"models,omitempty"`
+		SystemPrompt string   `yaml:"system_prompt,omitempty"`
+	} `yaml:"spec"`
+}
+
+type ServerFolder struct {
+	ID       string                 `json:"id"`
+	Name     string                 `json:"name"`
+	ParentID *string                `json:"parent_id"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+func (f Folder) name() string {
+	if f.Spec.Name != "" {
+		return f.Spec.Name
+	}
+	return f.Metadata.Name
+}
+
+func getFolders() ([]ServerFolder, error) {
+	var folders []ServerFolder
+	err := apiRequest("GET", fmt.Sprintf("%s/api/v1/folders/", BASE_URL), nil, &folders)
+	return folders, err
+}
+
+func findFolder(folders []ServerFolder, name string, parentID *string) (ServerFolder, bool) {
+	for _, folder := range folders {
+		sameParent := (folder.ParentID == nil && parentID == nil) ||
+			(folder.ParentID != nil && parentID != nil && *folder.ParentID == *parentID)
+		if folder.Name == name && sameParent {
+			return folder, true
+		}
+	}
+	return ServerFolder{}, false
+}
+
+func folderParentID(folders []ServerFolder, parent string) (*string, error) {
+	if parent == "" {
+		return nil, nil
+	}
+	if folder, ok := findFolder(folders, parent, nil); ok {
+		return &folder.ID, nil
+	}
+	var created ServerFolder
+	payload := map[string]interface{}{"name": parent}
+	if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/folders/", BASE_URL), payload, &created); err != nil {
+		return nil, fmt.Errorf("failed to create parent folder %s: %v", parent, err)
+	}
+	return &created.ID, nil
+}
+
+func processFolder(config Folder) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	folders, err := getFolders()
+	if err != nil {
+		return err
+	}
+	parentID, err := folderParentID(folders, config.Spec.Parent)
+	if err != nil {
+		return err
+	}
+
+	folder, exists := findFolder(folders, config.name(), parentID)
+	data := folder.Data
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	if config.Spec.Models != nil {
+		data["model_ids"] = config.Spec.Models
+	}
+	if config.Spec.SystemPrompt != "" {
+		data["system_prompt"] = config.Spec.SystemPrompt
+	}
+	payload := map[string]interface{}{
+		"name": config.name(),
+		"data": data,
+		"meta": map[string]interface{}{
+			"labels":      config.Metadata.Labels,
+			"annotations": config.Metadata.Annotations,
+		},
+	}
+
+	if exists {
+		return apiRequest("POST", fmt.Sprintf("%s/api/v1/folders/%s/update", BASE_URL, folder.ID), payload, nil)
+	}
+	if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/folders/", BASE_URL), payload, &folder); err != nil {
+		return err
+	}
+	if parentID != nil {
+		payload := map[string]interface{}{"parent_id": *parentID}
+		return apiRequest("POST", fmt.Sprintf("%s/api/v1/folders/%s/update/parent", BASE_URL, folder.ID), payload, nil)
+	}
+	return nil
+}
+
+func deleteFolder(config Folder) error {
+	folders, err := getFolders()
+	if err != nil {
+		return err
+	}
+	var parentID *string
+	if config.Spec.Parent != "" {
+		parent, ok := findFolder(folders, config.Spec.Parent, nil)
+		if !ok {
+			return nil
+		}
+		parentID = &parent.ID
+	}
+	folder, exists := findFolder(folders, config.name(), parentID)
+	if !exists {
+		return nil
+	}
+	return apiRequest("DELETE", fmt.Sprintf("%s/api/v1/folders/%s", BASE_URL, folder.ID), nil, nil)
+}
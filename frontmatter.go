@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type FrontMatterConfig struct {
+	Title    string   `yaml:"title,omitempty"`
+	Tags     string   `yaml:"tags,omitempty"`
+	Metadata []string `yaml:"metadata,omitempty"`
+}
+
+var markdownExtensions = []string{".md", ".markdown", ".mdx"}
+
+func readFrontMatter(filePath string) (map[string]interface{}, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() || strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff")) != "---" {
+		return nil, scanner.Err()
+	}
+	var block strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if trimmed := strings.TrimSpace(line); trimmed == "---" || trimmed == "..." {
+			fields := make(map[string]interface{})
+			if err := yaml.Unmarshal([]byte(block.String()), &fields); err != nil {
+				return nil, fmt.Errorf("invalid front matter: %v", err)
+			}
+			return fields, nil
+		}
+		block.WriteString(line + "\n")
+	}
+	return nil, scanner.Err()
+}
+
+func frontMatterList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			if s := strings.TrimSpace(frontMatterString(item)); s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func frontMatterString(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		var items []string
+		for _, item := range list {
+			items = append(items, frontMatterString(item))
+		}
+		return strings.Join(items, ", ")
+	}
+	switch v := value.(type) {
+	case time.Time:
+		if v.Equal(v.Truncate(24 * time.Hour)) {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	case string, int, float64, bool:
+		return fmt.Sprint(v)
+	}
+	return jsonValueString(value)
+}
+
+func applyFrontMatter(config *FrontMatterConfig, files []sourceFile) {
+	if config == nil {
+		return
+	}
+	titleKey, tagsKey := config.Title, config.Tags
+	if titleKey == "" {
+		titleKey = "title"
+	}
+	if tagsKey == "" {
+		tagsKey = "tags"
+	}
+	for i, file := range files {
+		if !containsString(markdownExtensions, strings.ToLower(filepath.Ext(file.Filename))) {
+			continue
+		}
+		fields, err := readFrontMatter(file.Path)
+		if err != nil {
+			logf("Ignoring the front matter of %s: %v\n", file.Filename, err)
+			continue
+		}
+		if fields == nil {
+			continue
+		}
+		if title := strings.TrimSpace(frontMatterString(fields[titleKey])); title != "" {
+			files[i].Title = title
+		}
+		for _, tag := range frontMatterList(fields[tagsKey]) {
+			if !containsString(files[i].Tags, tag) {
+				files[i].Tags = append(files[i].Tags, tag)
+			}
+		}
+		for _, key := range config.Metadata {
+			value, ok := fields[key]
+			if !ok {
+				continue
+			}
+			if files[i].Metadata == nil {
+				files[i].Metadata = make(map[string]string)
+			}
+			files[i].Metadata[key] = frontMatterString(value)
+		}
+	}
+}
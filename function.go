@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+)
+
+type Function struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Type            string                 `yaml:"type,omitempty"`
+		Name            string                 `yaml:"name,omitempty"`
+		Description     string                 `yaml:"description,omitempty"`
+		File            string                 `yaml:"file,omitempty"`
+		Content         string                 `yaml:"content,omitempty"`
+		Enabled         *bool                  `yaml:"enabled,omitempty"`
+		Global          *bool                  `yaml:"global,omitempty"`
+		Valves          map[string]interface{} `yaml:"valves,omitempty"`
+		TokenUserValves map[string]interface{} `yaml:"tokenUserValves,omitempty"`
+	} `yaml:"spec"`
+}
+
+type ServerFunction struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	IsActive bool   `json:"is_active"`
+	IsGlobal bool   `json:"is_global"`
+}
+
+func functionUrl(id, action string) string {
+	return fmt.Sprintf("%s/api/v1/functions/id/%s%s", BASE_URL, url.PathEscape(id), action)
+}
+
+func getFunction(id string) (ServerFunction, error) {
+	var function ServerFunction
+	err := apiRequest("GET", functionUrl(id, ""), nil, &function)
+	return function, err
+}
+
+func processFunction(config Function, manifestPath string) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	content := config.Spec.Content
+	if config.Spec.File != "" {
+		var err error
+		if content, err = readSpecFile(manifestPath, config.Spec.File); err != nil {
+			return err
+		}
+	}
+	if content == "" {
+		return fmt.Errorf("function %s needs either spec.file or spec.content", config.Metadata.Name)
+	}
+
+	name := config.Spec.Name
+	if name == "" {
+		name = config.Metadata.Name
+	}
+	payload := map[string]interface{}{
+		"id":      config.Metadata.Name,
+		"name":    name,
+		"content": content,
+		"meta": map[string]interface{}{
+			"description": config.Spec.Description,
+			"manifest":    map[string]interface{}{},
+			"labels":      config.Metadata.Labels,
+			"annotations": config.Metadata.Annotations,
+		},
+	}
+
+	if _, err := getFunction(config.Metadata.Name); err == nil {
+		err = apiRequest("POST", functionUrl(config.Metadata.Name, "/update"), payload, nil)
+		if err != nil {
+			return err
+		}
+	} else if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/functions/create", BASE_URL), payload, nil); err != nil {
+		return err
+	}
+
+	function, err := getFunction(config.Metadata.Name)
+	if err != nil {
+		return err
+	}
+	if config.Spec.Type != "" && function.Type != "" && config.Spec.Type != function.Type {
+		logf("Warning: function %s is declared as %s but the server detected %s\n", config.Metadata.Name, config.Spec.Type, function.Type)
+	}
+	err = updateValves(func(action string) string {
+		return functionUrl(config.Metadata.Name, action)
+	}, config.Spec.Valves, config.Spec.TokenUserValves)
+	if err != nil {
+		return err
+	}
+	if config.Spec.Enabled != nil && *config.Spec.Enabled != function.IsActive {
+		if err := apiRequest("POST", functionUrl(config.Metadata.Name, "/toggle"), nil, nil); err != nil {
+			return err
+		}
+	}
+	if config.Spec.Global != nil && *config.Spec.Global != function.IsGlobal {
+		if err := apiRequest("POST", functionUrl(config.Metadata.Name, "/toggle/global"), nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteFunction(config Function) error {
+	return apiRequest("DELETE", functionUrl(config.Metadata.Name, "/delete"), nil, nil)
+}
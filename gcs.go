@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+func isGcsSource(source string) bool {
+	return strings.HasPrefix(source, "gs://")
+}
+
+type googleCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenUri     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func requestGoogleToken(req *http.Request) (string, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to get Google access token: %s - %s", resp.Status, string(respBody))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	registerSecret(token.AccessToken)
+	return token.AccessToken, nil
+}
+
+func serviceAccountAssertion(creds googleCredentials) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("invalid private key in service account credentials")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("service account private key is not an RSA key")
+	}
+
+	now := time.Now().Unix()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": gcsScope,
+		"aud":   creds.TokenUri,
+		"iat":   now,
+		"exp":   now + 3600,
+	})
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func googleAccessToken() (string, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) && os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") == "" {
+		req, err := http.NewRequest("GET", "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		token, err := requestGoogleToken(req)
+		if err != nil {
+			return "", fmt.Errorf("no application default credentials found: %v", err)
+		}
+		return token, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var creds googleCredentials
+	if err := json.Unmarshal(content, &creds); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	form := url.Values{}
+	tokenUri := "https://oauth2.googleapis.com/token"
+	switch creds.Type {
+	case "service_account":
+		if creds.TokenUri != "" {
+			tokenUri = creds.TokenUri
+		}
+		creds.TokenUri = tokenUri
+		assertion, err := serviceAccountAssertion(creds)
+		if err != nil {
+			return "", err
+		}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+	case "authorized_user":
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", creds.ClientID)
+		form.Set("client_secret", creds.ClientSecret)
+		form.Set("refresh_token", creds.RefreshToken)
+	default:
+		return "", fmt.Errorf("unsupported credentials type %s in %s", creds.Type, path)
+	}
+
+	req, err := http.NewRequest("POST", tokenUri, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return requestGoogleToken(req)
+}
+
+func gcsRequest(token, rawUrl string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GET %s: %s - %s", rawUrl, resp.Status, string(respBody))
+	}
+	return resp, nil
+}
+
+func listGcsObjects(token, bucket, prefix string) ([]string, error) {
+	var names []string
+	pageToken := ""
+	for {
+		query := url.Values{"prefix": {prefix}, "fields": {"items(name),nextPageToken"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		resp, err := gcsRequest(token, fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o?%s", url.PathEscape(bucket), query.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+		if page.NextPageToken == "" {
+			return names, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func downloadGcsObject(token, bucket, name, target string) error {
+	resp, err := gcsRequest(token, fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o/%s?alt=media", url.PathEscape(bucket), url.PathEscape(name)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
+
+func handleGcsSource(source DocumentSource) ([]string, string, error) {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(source.Source, "gs://"), "/")
+	if bucket == "" {
+		return nil, "", fmt.Errorf("invalid source %s, expected gs://bucket/prefix", source.Source)
+	}
+	prefixes := []string{prefix}
+	if len(source.Dir) > 0 {
+		prefixes = nil
+		for _, dir := range source.Dir {
+			prefixes = append(prefixes, strings.TrimPrefix(prefix+"/"+strings.TrimPrefix(dir, "/"), "/"))
+		}
+	}
+
+	token, err := googleAccessToken()
+	if err != nil {
+		return nil, "", err
+	}
+	tempDir, err := os.MkdirTemp("", "oictl_gcs_")
+	if err != nil {
+		return nil, "", err
+	}
+
+	var files []string
+	for _, p := range prefixes {
+		names, err := listGcsObjects(token, bucket, p)
+		if err != nil {
+			os.RemoveAll(tempDir)
+			return nil, "", err
+		}
+		for _, name := range names {
+			if strings.HasSuffix(name, "/") || (len(source.Extensions) > 0 && !hasExtension(name, source.Extensions)) {
+				continue
+			}
+			target := filepath.Join(tempDir, filepath.FromSlash(filepath.Clean("/"+name)))
+			if err := downloadGcsObject(token, bucket, name, target); err != nil {
+				os.RemoveAll(tempDir)
+				return nil, "", err
+			}
+			files = append(files, target)
+		}
+	}
+	return files, tempDir, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func gitAuthEnv(source DocumentSource) ([]string, string, error) {
+	var env []string
+	remote := source.Source
+	if source.SshKeyPath != "" || source.SshKnownHosts != "" {
+		command := []string{"ssh", "-o", "BatchMode=yes"}
+		if source.SshKeyPath != "" {
+			command = append(command, "-i", shellQuote(source.SshKeyPath), "-o", "IdentitiesOnly=yes")
+		}
+		if source.SshKnownHosts != "" {
+			command = append(command, "-o", "UserKnownHostsFile="+shellQuote(source.SshKnownHosts), "-o", "StrictHostKeyChecking=yes")
+		}
+		env = append(env, "GIT_SSH_COMMAND="+strings.Join(command, " "))
+	}
+	if source.HttpsTokenRef != "" {
+		if !isUrlSource(source.Source) {
+			return nil, "", fmt.Errorf("httpsTokenRef requires an https:// git source, got %s", source.Source)
+		}
+		token, err := resolveSecretRef(source.HttpsTokenRef)
+		if err != nil {
+			return nil, "", err
+		}
+		username := source.Username
+		if username == "" {
+			username = "x-access-token"
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + token))
+		registerSecret(credentials)
+		env = append(env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials)
+		if parsed, err := url.Parse(source.Source); err == nil {
+			parsed.User = url.UserPassword(username, token)
+			remote = parsed.String()
+		}
+	}
+	return env, remote, nil
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var githubNextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+func isGithubSource(source string) bool {
+	return strings.HasPrefix(source, "github:")
+}
+
+type githubLabel struct {
+	Name string `json:"name"`
+}
+
+type githubIssue struct {
+	Number      int           `json:"number"`
+	Title       string        `json:"title"`
+	Body        string        `json:"body"`
+	State       string        `json:"state"`
+	HtmlUrl     string        `json:"html_url"`
+	Comments    int           `json:"comments"`
+	CommentsUrl string        `json:"comments_url"`
+	CreatedAt   string        `json:"created_at"`
+	Labels      []githubLabel `json:"labels"`
+	User        struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+type githubComment struct {
+	Body string `json:"body"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+}
+
+func githubRequest(token, method, rawUrl string, payload interface{}) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, rawUrl, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s %s: %s - %s", method, rawUrl, resp.Status, string(respBody))
+	}
+	return resp, nil
+}
+
+func githubList(token, rawUrl string, out func(json.RawMessage) error) error {
+	for rawUrl != "" {
+		resp, err := githubRequest(token, "GET", rawUrl, nil)
+		if err != nil {
+			return err
+		}
+		var page []json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, item := range page {
+			if err := out(item); err != nil {
+				return err
+			}
+		}
+		rawUrl = ""
+		if match := githubNextLink.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+			rawUrl = match[1]
+		}
+	}
+	return nil
+}
+
+func labelNames(labels []githubLabel) []string {
+	var names []string
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+	return names
+}
+
+func githubIssueDocument(token string, issue githubIssue) (string, error) {
+	kind := "Issue"
+	if issue.PullRequest != nil {
+		kind = "Pull request"
+	}
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "# %s #%d: %s\n\n", kind, issue.Number, issue.Title)
+	fmt.Fprintf(&doc, "- Link: %s\n- State: %s\n- Author: %s\n- Created: %s\n", issue.HtmlUrl, issue.State, issue.User.Login, issue.CreatedAt)
+	if len(issue.Labels) > 0 {
+		fmt.Fprintf(&doc, "- Labels: %s\n", strings.Join(labelNames(issue.Labels), ", "))
+	}
+	if body := strings.TrimSpace(issue.Body); body != "" {
+		fmt.Fprintf(&doc, "\n%s\n", body)
+	}
+
+	if issue.PullRequest == nil && issue.Comments > 0 {
+		doc.WriteString("\n## Comments\n")
+		err := githubList(token, issue.CommentsUrl+"?per_page=100", func(raw json.RawMessage) error {
+			var comment githubComment
+			if err := json.Unmarshal(raw, &comment); err != nil {
+				return err
+			}
+			fmt.Fprintf(&doc, "\n### %s\n\n%s\n", comment.User.Login, strings.TrimSpace(comment.Body))
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return doc.String(), nil
+}
+
+func githubDiscussions(token, owner, repo string, add func(name, content string, labels []string) error) error {
+	query := `query($owner: String!, $repo: String!, $after: String) {
+  repository(owner: $owner, name: $repo) {
+    discussions(first: 50, after: $after) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        number title body url createdAt
+        author { login }
+        category { name }
+        labels(first: 20) { nodes { name } }
+        answer { body author { login } }
+        comments(first: 50) { nodes { body author { login } } }
+      }
+    }
+  }
+}`
+	var after interface{}
+	for {
+		payload := map[string]interface{}{
+			"query":     query,
+			"variables": map[string]interface{}{"owner": owner, "repo": repo, "after": after},
+		}
+		resp, err := githubRequest(token, "POST", "https://api.github.com/graphql", payload)
+		if err != nil {
+			return err
+		}
+		var result struct {
+			Data struct {
+				Repository struct {
+					Discussions struct {
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+						Nodes []struct {
+							Number    int    `json:"number"`
+							Title     string `json:"title"`
+							Body      string `json:"body"`
+							Url       string `json:"url"`
+							CreatedAt string `json:"createdAt"`
+							Author    struct {
+								Login string `json:"login"`
+							} `json:"author"`
+							Category struct {
+								Name string `json:"name"`
+							} `json:"category"`
+							Labels struct {
+								Nodes []githubLabel `json:"nodes"`
+							} `json:"labels"`
+							Answer   *githubComment `json:"answer"`
+							Comments struct {
+								Nodes []githubComment `json:"nodes"`
+							} `json:"comments"`
+						} `json:"nodes"`
+					} `json:"discussions"`
+				} `json:"repository"`
+			} `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("github discussions: %s", result.Errors[0].Message)
+		}
+
+		discussions := result.Data.Repository.Discussions
+		for _, d := range discussions.Nodes {
+			var doc strings.Builder
+			fmt.Fprintf(&doc, "# Discussion #%d: %s\n\n", d.Number, d.Title)
+			fmt.Fprintf(&doc, "- Link: %s\n- Category: %s\n- Author: %s\n- Created: %s\n", d.Url, d.Category.Name, d.Author.Login, d.CreatedAt)
+			fmt.Fprintf(&doc, "\n%s\n", strings.TrimSpace(d.Body))
+			if d.Answer != nil {
+				fmt.Fprintf(&doc, "\n## Answer (%s)\n\n%s\n", d.Answer.Author.Login, strings.TrimSpace(d.Answer.Body))
+			}
+			if len(d.Comments.Nodes) > 0 {
+				doc.WriteString("\n## Comments\n")
+				for _, comment := range d.Comments.Nodes {
+					fmt.Fprintf(&doc, "\n### %s\n\n%s\n", comment.Author.Login, strings.TrimSpace(comment.Body))
+				}
+			}
+			if err := add(fmt.Sprintf("discussion-%d.md", d.Number), doc.String(), labelNames(d.Labels.Nodes)); err != nil {
+				return err
+			}
+		}
+		if !discussions.PageInfo.HasNextPage {
+			return nil
+		}
+		after = discussions.PageInfo.EndCursor
+	}
+}
+
+func handleGithubSource(source DocumentSource) ([]sourceFile, string, error) {
+	owner, repo, ok := strings.Cut(strings.TrimPrefix(source.Source, "github:"), "/")
+	if !ok || owner == "" || repo == "" {
+		return nil, "", fmt.Errorf("invalid source %s, expected github:owner/repo", source.Source)
+	}
+	registerSecret(source.Token)
+	items := source.Items
+	if len(items) == 0 {
+		items = []string{"issues", "pulls", "discussions"}
+		if source.Token == "" {
+			logf("Skipping the discussions of %s: reading them needs a token\n", source.Source)
+			items = items[:2]
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "oictl_github_")
+	if err != nil {
+		return nil, "", err
+	}
+	var files []sourceFile
+	add := func(name, content string, labels []string) error {
+		target := filepath.Join(tempDir, name)
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return err
+		}
+		files = append(files, sourceFile{Path: target, Filename: name, Tags: labels})
+		logf("\rGitHub documents fetched: %d", len(files))
+		return nil
+	}
+
+	err = func() error {
+		issues, pulls := containsString(items, "issues"), containsString(items, "pulls")
+		if issues || pulls {
+			listUrl := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues?state=all&per_page=100", owner, repo)
+			err := githubList(source.Token, listUrl, func(raw json.RawMessage) error {
+				var issue githubIssue
+				if err := json.Unmarshal(raw, &issue); err != nil {
+					return err
+				}
+				name := fmt.Sprintf("issue-%d.md", issue.Number)
+				if issue.PullRequest != nil {
+					if !pulls {
+						return nil
+					}
+					name = fmt.Sprintf("pr-%d.md", issue.Number)
+				} else if !issues {
+					return nil
+				}
+				content, err := githubIssueDocument(source.Token, issue)
+				if err != nil {
+					return err
+				}
+				return add(name, content, labelNames(issue.Labels))
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if containsString(items, "discussions") {
+			if source.Token == "" {
+				return fmt.Errorf("github source %s needs a token to read discussions", source.Source)
+			}
+			return githubDiscussions(source.Token, owner, repo, add)
+		}
+		return nil
+	}()
+	if len(files) > 0 {
+		logf("\n")
+	}
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", err
+	}
+	return files, tempDir, nil
+}
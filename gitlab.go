@@ -0,0 +1,135 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func extractTarGz(r io.Reader, target string, stripComponents int) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(strings.Trim(filepath.ToSlash(header.Name), "/"), "/")
+		if len(parts) <= stripComponents {
+			continue
+		}
+		name := filepath.Join(target, filepath.FromSlash(filepath.Clean("/"+strings.Join(parts[stripComponents:], "/"))))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(name, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+				return err
+			}
+			file, err := os.Create(name)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, archive)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func downloadGitlabArchive(project *url.URL, token, ref, target string) error {
+	projectPath := strings.Trim(project.Path, "/")
+	archiveUrl := fmt.Sprintf("%s://%s/api/v4/projects/%s/repository/archive.tar.gz", project.Scheme, project.Host, url.PathEscape(projectPath))
+	if ref != "" {
+		archiveUrl += "?sha=" + url.QueryEscape(ref)
+	}
+	req, err := http.NewRequest("GET", archiveUrl, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("PRIVATE-TOKEN", token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to download archive of %s: %s - %s", projectPath, resp.Status, string(respBody))
+	}
+	return extractTarGz(resp.Body, target, 1)
+}
+
+func handleGitlabSource(source DocumentSource) ([]string, string, error) {
+	project, err := url.Parse(strings.TrimSuffix(strings.TrimSuffix(source.Source, "/"), ".git"))
+	if err != nil || project.Host == "" || strings.Trim(project.Path, "/") == "" {
+		return nil, "", fmt.Errorf("invalid gitlab source %s, expected https://<host>/<group>[/<subgroup>...]/<project>", source.Source)
+	}
+	registerSecret(source.Token)
+
+	tempDir, err := os.MkdirTemp("", "oictl_gitlab_")
+	if err != nil {
+		return nil, "", err
+	}
+	cloneUrl := *project
+	cloneUrl.Path += ".git"
+	_, lookErr := exec.LookPath("git")
+	cloned := lookErr == nil || useGoGit()
+	if cloned {
+		if source.Token != "" {
+			cloneUrl.User = url.UserPassword("oauth2", source.Token)
+		}
+		cloneSource := source
+		cloneSource.Source = cloneUrl.String()
+		if useGoGit() {
+			err = goGitClone(cloneSource, tempDir)
+		} else {
+			err = cloneGitSource(cloneSource, tempDir, nil)
+		}
+	} else {
+		logf("git not found, downloading the archive of %s through the GitLab API\n", project.Path)
+		err = downloadGitlabArchive(project, source.Token, source.Ref, tempDir)
+	}
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", err
+	}
+
+	dirs := source.Dir
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	files, err := collectRepoFiles(tempDir, dirs, source)
+	if err == nil && cloned {
+		err = fetchLfsObjects(tempDir, cloneUrl.String(), nil, files)
+	}
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", err
+	}
+	return files, tempDir, nil
+}
@@ -1,8 +1,9 @@
 module main
 
-go 1.22.2
+go 1.25.0
 
 require (
+	github.com/go-git/go-git/v5 v5.19.2
 	github.com/google/uuid v1.6.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,141 @@
+//go:build !gitbinary
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+const goGitAvailable = true
+
+func goGitAuth(source DocumentSource) (transport.AuthMethod, error) {
+	if source.HttpsTokenRef != "" {
+		token, err := resolveSecretRef(source.HttpsTokenRef)
+		if err != nil {
+			return nil, err
+		}
+		username := source.Username
+		if username == "" {
+			username = "x-access-token"
+		}
+		return &githttp.BasicAuth{Username: username, Password: token}, nil
+	}
+	if source.SshKeyPath == "" && source.SshKnownHosts == "" {
+		return nil, nil
+	}
+	if isUrlSource(source.Source) {
+		return nil, nil
+	}
+	user := "git"
+	if parsed, err := url.Parse(source.Source); err == nil && parsed.User != nil {
+		user = parsed.User.Username()
+	} else if at := strings.Index(source.Source, "@"); at > 0 && !strings.Contains(source.Source[:at], ":") {
+		user = source.Source[:at]
+	}
+	var auth *gitssh.PublicKeys
+	var err error
+	if source.SshKeyPath != "" {
+		if auth, err = gitssh.NewPublicKeysFromFile(user, source.SshKeyPath, ""); err != nil {
+			return nil, fmt.Errorf("failed to read ssh key %s: %v", source.SshKeyPath, err)
+		}
+	}
+	if auth == nil {
+		agent, err := gitssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, err
+		}
+		if source.SshKnownHosts != "" {
+			if agent.HostKeyCallback, err = gitssh.NewKnownHostsCallback(source.SshKnownHosts); err != nil {
+				return nil, err
+			}
+		}
+		return agent, nil
+	}
+	if source.SshKnownHosts != "" {
+		if auth.HostKeyCallback, err = gitssh.NewKnownHostsCallback(source.SshKnownHosts); err != nil {
+			return nil, err
+		}
+	}
+	return auth, nil
+}
+
+func goGitClone(source DocumentSource, localPath string) error {
+	auth, err := goGitAuth(source)
+	if err != nil {
+		return err
+	}
+	options := &git.CloneOptions{URL: source.Source, Auth: auth}
+	if !source.FullClone {
+		options.Depth = 1
+		options.SingleBranch = true
+	}
+	if source.Ref != "" && !source.FullClone {
+		for _, name := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(source.Ref), plumbing.NewTagReferenceName(source.Ref)} {
+			options.ReferenceName = name
+			if _, err = git.PlainClone(localPath, false, options); err == nil {
+				return nil
+			}
+			os.RemoveAll(localPath)
+		}
+		options.Depth, options.SingleBranch, options.ReferenceName = 0, false, ""
+	}
+	if _, err := git.PlainClone(localPath, false, options); err != nil {
+		return fmt.Errorf("git clone %s: %v", source.Source, err)
+	}
+	return goGitCheckout(localPath, source.Ref, auth)
+}
+
+func goGitCheckout(localPath, ref string, auth transport.AuthMethod) error {
+	if ref == "" {
+		return nil
+	}
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		hash, err = repo.ResolveRevision(plumbing.Revision("origin/" + ref))
+	}
+	if err != nil {
+		fetched := plumbing.ReferenceName("refs/oictl/fetched")
+		err = repo.Fetch(&git.FetchOptions{RemoteName: "origin", Auth: auth, RefSpecs: []config.RefSpec{config.RefSpec(ref + ":" + fetched.String())}})
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return fmt.Errorf("git fetch %s: %v", ref, err)
+		}
+		hash, err = repo.ResolveRevision(plumbing.Revision(fetched))
+	}
+	if err != nil {
+		return fmt.Errorf("git checkout %s: %v", ref, err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: *hash}); err != nil {
+		return fmt.Errorf("git checkout %s: %v", ref, err)
+	}
+	return nil
+}
+
+func goGitHead(dir string) string {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return ""
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return ""
+	}
+	return head.Hash().String()
+}
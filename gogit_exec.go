@@ -0,0 +1,19 @@
+//go:build gitbinary
+
+package main
+
+import "fmt"
+
+const goGitAvailable = false
+
+func goGitClone(source DocumentSource, localPath string) error {
+	return fmt.Errorf("oictl was built without go-git support")
+}
+
+func goGitCheckout(localPath, ref string, auth interface{}) error {
+	return fmt.Errorf("oictl was built without go-git support")
+}
+
+func goGitHead(dir string) string {
+	return ""
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type graphNode struct {
+	ID    string
+	Label string
+	Kind  string
+}
+
+type graphEdge struct {
+	From  string
+	To    string
+	Label string
+}
+
+type resourceGraph struct {
+	nodes map[string]graphNode
+	edges []graphEdge
+}
+
+func newResourceGraph() *resourceGraph {
+	return &resourceGraph{nodes: make(map[string]graphNode)}
+}
+
+func (g *resourceGraph) addNode(kind, name string) string {
+	id := fmt.Sprintf("%s/%s", kind, name)
+	if _, ok := g.nodes[id]; !ok {
+		g.nodes[id] = graphNode{ID: id, Label: name, Kind: kind}
+	}
+	return id
+}
+
+func (g *resourceGraph) addEdge(from, to, label string) {
+	for _, e := range g.edges {
+		if e.From == from && e.To == to && e.Label == label {
+			return
+		}
+	}
+	g.edges = append(g.edges, graphEdge{From: from, To: to, Label: label})
+}
+
+func buildResourceGraph(manifests []manifest) *resourceGraph {
+	g := newResourceGraph()
+	for _, m := range manifests {
+		node := g.addNode(m.Kind, m.Metadata.Name)
+		for _, dep := range m.Metadata.DependsOn {
+			for _, i := range resolveDependency(dep, manifests) {
+				g.addEdge(node, g.addNode(manifests[i].Kind, manifests[i].Metadata.Name), "dependsOn")
+			}
+		}
+
+		switch c := m.Config.(type) {
+		case Folder:
+			folder := g.addNode("Folder", c.Metadata.Name)
+			for _, id := range c.Spec.Models {
+				g.addEdge(folder, g.addNode("Model", id), "models")
+			}
+		case ArenaModel:
+			arena := g.addNode("ArenaModel", c.Metadata.Name)
+			for _, id := range c.Spec.Models {
+				g.addEdge(arena, g.addNode("Model", id), "models")
+			}
+		case Documents:
+			docs := g.addNode("Documents", c.Metadata.Name)
+			tags := c.sourceTags(DocumentSource{})
+			for _, source := range c.Spec.Sources {
+				for _, tag := range c.sourceTags(source) {
+					if !containsString(tags, tag) {
+						tags = append(tags, tag)
+					}
+				}
+			}
+			for _, tag := range tags {
+				g.addEdge(docs, g.addNode("Tag", tag), "tags")
+			}
+		case Model:
+			model := g.addNode("Model", c.Metadata.Name)
+			if c.Spec.BaseModelID != "" {
+				g.addEdge(model, g.addNode("BaseModel", c.Spec.BaseModelID), "base")
+			}
+			for _, knowledge := range c.Spec.Meta.Knowledge {
+				if knowledge.Knowledge != "" {
+					g.addEdge(model, g.addNode("Knowledge", knowledge.Knowledge), "knowledge")
+					continue
+				}
+				g.addEdge(model, g.addNode("Tag", knowledge.Tags), "knowledge")
+			}
+			for _, id := range c.Spec.Meta.ToolIDs {
+				g.addEdge(model, g.addNode("Tool", id), "tool")
+			}
+			for _, id := range c.Spec.Meta.FilterIDs {
+				g.addEdge(model, g.addNode("Function", id), "filter")
+			}
+			for _, id := range c.Spec.Meta.ActionIDs {
+				g.addEdge(model, g.addNode("Function", id), "action")
+			}
+		}
+	}
+	return g
+}
+
+func (g *resourceGraph) sortedNodes() []graphNode {
+	var nodes []graphNode
+	for _, n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+var graphShapes = map[string]string{
+	"Model":      "box",
+	"ArenaModel": "box3d",
+	"Documents":  "folder",
+	"Knowledge":  "folder",
+	"Tag":        "ellipse",
+	"BaseModel":  "component",
+	"Tool":       "hexagon",
+	"Function":   "octagon",
+	"Pipeline":   "cds",
+	"Folder":     "tab",
+}
+
+func (g *resourceGraph) writeDot(w io.Writer) {
+	fmt.Fprintln(w, "digraph oictl {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for _, n := range g.sortedNodes() {
+		shape := graphShapes[n.Kind]
+		if shape == "" {
+			shape = "box"
+		}
+		fmt.Fprintf(w, "  %q [label=%q, shape=%s];\n", n.ID, fmt.Sprintf("%s\n%s", n.Kind, n.Label), shape)
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(w, "  %q -> %q [label=%q];\n", e.From, e.To, e.Label)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func d2Key(id string) string {
+	return fmt.Sprintf("%q", strings.ReplaceAll(id, "/", "_"))
+}
+
+func (g *resourceGraph) writeD2(w io.Writer) {
+	for _, n := range g.sortedNodes() {
+		fmt.Fprintf(w, "%s: %q\n", d2Key(n.ID), fmt.Sprintf("%s: %s", n.Kind, n.Label))
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(w, "%s -> %s: %s\n", d2Key(e.From), d2Key(e.To), e.Label)
+	}
+}
+
+func runGraph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	file := fs.String("f", "", "path to a definition file or directory")
+	format := fs.String("o", "dot", "output format: dot or d2")
+	mf := addManifestFlags(fs)
+	fs.Parse(args)
+
+	opts, err := mf.options()
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return fmt.Errorf("no definition path given, use -f")
+	}
+
+	paths, err := collectPaths(*file, &opts)
+	if err != nil {
+		return err
+	}
+	g := buildResourceGraph(loadManifests(paths, opts))
+
+	switch *format {
+	case "dot":
+		g.writeDot(os.Stdout)
+	case "d2":
+		g.writeD2(os.Stdout)
+	default:
+		return fmt.Errorf("unknown graph format %s", *format)
+	}
+	return nil
+}
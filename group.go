@@ -0,0 +1,108 @@
+package main
+
+import "fmt"
+
+type Group struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Description string                 `yaml:"description,omitempty"`
+		Members     *[]string              `yaml:"members,omitempty"`
+		Permissions map[string]interface{} `yaml:"permissions,omitempty"`
+	} `yaml:"spec"`
+}
+
+func findGroup(name string) (ServerGroup, bool, error) {
+	groups, err := getGroups()
+	if err != nil {
+		return ServerGroup{}, false, err
+	}
+	for _, group := range groups {
+		if group.Name == name {
+			return group, true, nil
+		}
+	}
+	return ServerGroup{}, false, nil
+}
+
+func processGroup(config Group) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	group, exists, err := findGroup(config.Metadata.Name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		payload := map[string]interface{}{
+			"name":        config.Metadata.Name,
+			"description": config.Spec.Description,
+		}
+		if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/groups/create", BASE_URL), payload, &group); err != nil {
+			return err
+		}
+	}
+
+	group.Description = config.Spec.Description
+	if config.Spec.Permissions != nil {
+		group.Permissions = config.Spec.Permissions
+	}
+	planned := plannedMemberships.groupMembers(group)
+	if config.Spec.Members == nil && len(planned) > 0 {
+		users, err := getUsers()
+		if err != nil {
+			return err
+		}
+		plannedIDs, err := resolveUserIDs(planned, users)
+		if err != nil {
+			return err
+		}
+		for _, id := range plannedIDs {
+			if !containsString(group.UserIDs, id) {
+				group.UserIDs = append(group.UserIDs, id)
+			}
+		}
+	}
+	if config.Spec.Members != nil {
+		users, err := getUsers()
+		if err != nil {
+			return err
+		}
+		memberIDs, err := resolveUserIDs(append(append([]string{}, *config.Spec.Members...), planned...), users)
+		if err != nil {
+			return err
+		}
+		var unique []string
+		for _, id := range memberIDs {
+			if !containsString(unique, id) {
+				unique = append(unique, id)
+			}
+		}
+		memberIDs = unique
+		added, removed := 0, 0
+		for _, id := range memberIDs {
+			if !containsString(group.UserIDs, id) {
+				added++
+			}
+		}
+		for _, id := range group.UserIDs {
+			if !containsString(memberIDs, id) {
+				removed++
+			}
+		}
+		if added > 0 || removed > 0 {
+			logf("Group %s: %d members added, %d removed\n", config.Metadata.Name, added, removed)
+		}
+		group.UserIDs = memberIDs
+	}
+	return updateGroup(group)
+}
+
+func deleteGroup(config Group) error {
+	group, exists, err := findGroup(config.Metadata.Name)
+	if err != nil || !exists {
+		return err
+	}
+	return apiRequest("DELETE", fmt.Sprintf("%s/api/v1/groups/id/%s/delete", BASE_URL, group.ID), nil, nil)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const ignoreFileName = ".oictlignore"
+
+var defaultIgnorePatterns = []string{".git/", ignoreFileName}
+
+type ignorePattern struct {
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+type ignoreMatcher struct {
+	root      string
+	fileNames []string
+	patterns  []ignorePattern
+	loaded    map[string]bool
+	include   []*regexp.Regexp
+	exclude   []*regexp.Regexp
+}
+
+func newIgnoreMatcher(root string, fileNames ...string) *ignoreMatcher {
+	m := &ignoreMatcher{root: root, fileNames: fileNames, loaded: make(map[string]bool)}
+	for _, pattern := range defaultIgnorePatterns {
+		m.addPattern(root, pattern)
+	}
+	m.load(root)
+	return m
+}
+
+func sourceIgnoreMatcher(root string, source DocumentSource) (*ignoreMatcher, error) {
+	m := newIgnoreMatcher(root, ignoreFileName)
+	for _, glob := range source.Include {
+		re, err := compileGlob(glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q of source %s: %v", glob, source.Source, err)
+		}
+		m.include = append(m.include, re)
+	}
+	for _, glob := range source.Exclude {
+		re, err := compileGlob(glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q of source %s: %v", glob, source.Source, err)
+		}
+		m.exclude = append(m.exclude, re)
+	}
+	return m, nil
+}
+
+func (m *ignoreMatcher) load(dir string) {
+	if m == nil || m.loaded[dir] {
+		return
+	}
+	m.loaded[dir] = true
+	for _, name := range m.fileNames {
+		m.loadFile(dir, filepath.Join(dir, name))
+	}
+}
+
+func (m *ignoreMatcher) loadFile(base, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		m.addPattern(base, scanner.Text())
+	}
+}
+
+func gitRepoRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func localIgnoreMatcher(root string, source DocumentSource) (*ignoreMatcher, error) {
+	m, err := sourceIgnoreMatcher(root, source)
+	if err != nil || (source.RespectGitignore != nil && !*source.RespectGitignore) {
+		return m, err
+	}
+	repoRoot := gitRepoRoot(root)
+	if repoRoot == "" {
+		return m, nil
+	}
+
+	m.loadFile(repoRoot, filepath.Join(repoRoot, ".git", "info", "exclude"))
+	rel, _ := filepath.Rel(repoRoot, root)
+	dir := repoRoot
+	m.loadFile(dir, filepath.Join(dir, ".gitignore"))
+	if rel != "." {
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			dir = filepath.Join(dir, part)
+			m.loadFile(dir, filepath.Join(dir, ".gitignore"))
+		}
+	}
+	m.fileNames = append(m.fileNames, ".gitignore")
+	return m, nil
+}
+
+func (m *ignoreMatcher) addPattern(base, line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	p := ignorePattern{base: base}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	re, err := compileGlob(line)
+	if err != nil {
+		return
+	}
+	p.re = re
+	m.patterns = append(m.patterns, p)
+}
+
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(strings.TrimSuffix(glob, "/"), "/")
+	glob = strings.TrimPrefix(glob, "/")
+
+	expr := globToRegexp(glob)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(?:.*/)?" + expr + "$"
+	}
+	return regexp.Compile(expr)
+}
+
+func matchesAny(patterns []*regexp.Regexp, path string) bool {
+	for _, re := range patterns {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end == -1 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+func (m *ignoreMatcher) ignored(path string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(p.base, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if p.re.MatchString(filepath.ToSlash(rel)) {
+			ignored = !p.negate
+		}
+	}
+	if ignored || (len(m.include) == 0 && len(m.exclude) == 0) {
+		return ignored
+	}
+
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if isDir {
+		return matchesAny(m.exclude, rel) || matchesAny(m.exclude, rel+"/")
+	}
+	return matchesAny(m.exclude, rel) || (len(m.include) > 0 && !matchesAny(m.include, rel))
+}
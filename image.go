@@ -0,0 +1,66 @@
+package main
+
+import "fmt"
+
+type ImageGenerationConfig struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Enabled          *bool  `yaml:"enabled,omitempty"`
+		Engine           string `yaml:"engine"`
+		BaseUrl          string `yaml:"baseUrl,omitempty"`
+		ApiKey           string `yaml:"apiKey,omitempty"`
+		ApiKeyEnv        string `yaml:"apiKeyEnv,omitempty"`
+		Model            string `yaml:"model,omitempty"`
+		Size             string `yaml:"size,omitempty"`
+		Steps            *int   `yaml:"steps,omitempty"`
+		PromptGeneration *bool  `yaml:"promptGeneration,omitempty"`
+	} `yaml:"spec"`
+}
+
+func processImageGenerationConfig(config ImageGenerationConfig) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	key, err := resolveApiKey(config.Spec.ApiKey, config.Spec.ApiKeyEnv)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{
+		"enabled":           config.Spec.Enabled,
+		"engine":            config.Spec.Engine,
+		"prompt_generation": config.Spec.PromptGeneration,
+	}
+	switch config.Spec.Engine {
+	case "":
+	case "openai":
+		fields["openai"] = map[string]interface{}{
+			"OPENAI_API_BASE_URL": config.Spec.BaseUrl,
+			"OPENAI_API_KEY":      key,
+		}
+	case "automatic1111":
+		fields["automatic1111"] = map[string]interface{}{
+			"AUTOMATIC1111_BASE_URL": config.Spec.BaseUrl,
+			"AUTOMATIC1111_API_AUTH": key,
+		}
+	case "comfyui":
+		fields["comfyui"] = map[string]interface{}{
+			"COMFYUI_BASE_URL": config.Spec.BaseUrl,
+			"COMFYUI_API_KEY":  key,
+		}
+	default:
+		return fmt.Errorf("unknown image generation engine %s", config.Spec.Engine)
+	}
+	if err := updateServerConfig("/api/v1/images/config", "/api/v1/images/config/update", fields); err != nil {
+		return err
+	}
+
+	image := map[string]interface{}{
+		"MODEL":       config.Spec.Model,
+		"IMAGE_SIZE":  config.Spec.Size,
+		"IMAGE_STEPS": config.Spec.Steps,
+	}
+	return updateServerConfig("/api/v1/images/image/config", "/api/v1/images/image/config/update", image)
+}
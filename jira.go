@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string   `json:"summary"`
+		Description string   `json:"description"`
+		Labels      []string `json:"labels"`
+		Created     string   `json:"created"`
+		Updated     string   `json:"updated"`
+		Status      struct {
+			Name string `json:"name"`
+		} `json:"status"`
+		IssueType struct {
+			Name string `json:"name"`
+		} `json:"issuetype"`
+		Reporter *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"reporter"`
+		Assignee *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"assignee"`
+		Comment struct {
+			Comments []struct {
+				Body    string `json:"body"`
+				Created string `json:"created"`
+				Author  struct {
+					DisplayName string `json:"displayName"`
+				} `json:"author"`
+			} `json:"comments"`
+		} `json:"comment"`
+	} `json:"fields"`
+}
+
+type jiraSearchResult struct {
+	Issues        []jiraIssue `json:"issues"`
+	Total         int         `json:"total"`
+	NextPageToken string      `json:"nextPageToken"`
+	IsLast        bool        `json:"isLast"`
+}
+
+func jiraSearch(source DocumentSource, endpoint string, query url.Values) (jiraSearchResult, int, error) {
+	var result jiraSearchResult
+	rawUrl := fmt.Sprintf("%s%s?%s", strings.TrimSuffix(source.Source, "/"), endpoint, query.Encode())
+	req, err := http.NewRequest("GET", rawUrl, nil)
+	if err != nil {
+		return result, 0, err
+	}
+	if source.Username != "" {
+		req.SetBasicAuth(source.Username, source.Token)
+	} else {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", source.Token))
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return result, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return result, resp.StatusCode, fmt.Errorf("jira search: %s - %s", resp.Status, string(respBody))
+	}
+	return result, resp.StatusCode, json.NewDecoder(resp.Body).Decode(&result)
+}
+
+func searchJiraIssues(source DocumentSource) ([]jiraIssue, error) {
+	endpoint := "/rest/api/2/search"
+	query := url.Values{
+		"jql":        {source.Query},
+		"fields":     {"summary,description,labels,created,updated,status,issuetype,reporter,assignee,comment"},
+		"maxResults": {"50"},
+	}
+
+	var issues []jiraIssue
+	for {
+		if endpoint == "/rest/api/2/search" {
+			query.Set("startAt", strconv.Itoa(len(issues)))
+		}
+		result, status, err := jiraSearch(source, endpoint, query)
+		if (status == http.StatusGone || status == http.StatusNotFound) && endpoint == "/rest/api/2/search" {
+			endpoint = "/rest/api/2/search/jql"
+			query.Del("startAt")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, result.Issues...)
+		logf("\rJira issues fetched: %d", len(issues))
+
+		if endpoint == "/rest/api/2/search" {
+			if len(result.Issues) == 0 || len(issues) >= result.Total {
+				break
+			}
+		} else {
+			if result.IsLast || result.NextPageToken == "" {
+				break
+			}
+			query.Set("nextPageToken", result.NextPageToken)
+		}
+	}
+	if len(issues) > 0 {
+		logf("\n")
+	}
+	return issues, nil
+}
+
+func jiraIssueDocument(issue jiraIssue, baseUrl string) string {
+	f := issue.Fields
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "# %s: %s\n\n", issue.Key, f.Summary)
+	fmt.Fprintf(&doc, "- Link: %s/browse/%s\n", strings.TrimSuffix(baseUrl, "/"), issue.Key)
+	fmt.Fprintf(&doc, "- Type: %s\n- Status: %s\n", f.IssueType.Name, f.Status.Name)
+	if f.Reporter != nil {
+		fmt.Fprintf(&doc, "- Reporter: %s\n", f.Reporter.DisplayName)
+	}
+	if f.Assignee != nil {
+		fmt.Fprintf(&doc, "- Assignee: %s\n", f.Assignee.DisplayName)
+	}
+	if len(f.Labels) > 0 {
+		fmt.Fprintf(&doc, "- Labels: %s\n", strings.Join(f.Labels, ", "))
+	}
+	fmt.Fprintf(&doc, "- Created: %s\n- Updated: %s\n", f.Created, f.Updated)
+	if f.Description != "" {
+		fmt.Fprintf(&doc, "\n## Description\n\n%s\n", strings.TrimSpace(f.Description))
+	}
+	if len(f.Comment.Comments) > 0 {
+		doc.WriteString("\n## Comments\n")
+		for _, comment := range f.Comment.Comments {
+			fmt.Fprintf(&doc, "\n### %s (%s)\n\n%s\n", comment.Author.DisplayName, comment.Created, strings.TrimSpace(comment.Body))
+		}
+	}
+	return doc.String()
+}
+
+func handleJiraSource(source DocumentSource) ([]string, string, error) {
+	if source.Query == "" {
+		return nil, "", fmt.Errorf("jira source %s needs a query (JQL)", source.Source)
+	}
+	if source.Token == "" {
+		return nil, "", fmt.Errorf("jira source %s needs a token", source.Source)
+	}
+	registerSecret(source.Token)
+
+	issues, err := searchJiraIssues(source)
+	if err != nil {
+		return nil, "", err
+	}
+	tempDir, err := os.MkdirTemp("", "oictl_jira_")
+	if err != nil {
+		return nil, "", err
+	}
+	var files []string
+	for _, issue := range issues {
+		target := filepath.Join(tempDir, issue.Key+".md")
+		if err := os.WriteFile(target, []byte(jiraIssueDocument(issue, source.Source)), 0644); err != nil {
+			os.RemoveAll(tempDir)
+			return nil, "", err
+		}
+		files = append(files, target)
+	}
+	return files, tempDir, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type Knowledge struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Description string           `yaml:"description,omitempty"`
+		Sources     []DocumentSource `yaml:"sources"`
+	} `yaml:"spec"`
+}
+
+type ServerKnowledge struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Files       []struct {
+		ID   string `json:"id"`
+		Meta struct {
+			Name string `json:"name"`
+		} `json:"meta"`
+	} `json:"files"`
+}
+
+func getKnowledgeBases() ([]ServerKnowledge, error) {
+	var bases []ServerKnowledge
+	err := apiRequest("GET", fmt.Sprintf("%s/api/v1/knowledge/", BASE_URL), nil, &bases)
+	return bases, err
+}
+
+func findKnowledgeBase(name string) (ServerKnowledge, bool, error) {
+	bases, err := getKnowledgeBases()
+	if err != nil {
+		return ServerKnowledge{}, false, err
+	}
+	for _, base := range bases {
+		if base.Name == name {
+			var full ServerKnowledge
+			err := apiRequest("GET", fmt.Sprintf("%s/api/v1/knowledge/%s", BASE_URL, base.ID), nil, &full)
+			return full, true, err
+		}
+	}
+	return ServerKnowledge{}, false, nil
+}
+
+func uploadFile(file, filename string) (string, error) {
+	resp, err := postFile(fmt.Sprintf("%s/api/v1/files/", BASE_URL), file, filename, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to upload file %s: %s - %s", file, resp.Status, string(respBody))
+	}
+
+	var uploaded struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		return "", err
+	}
+	return uploaded.ID, nil
+}
+
+func processKnowledge(config Knowledge, manifestPath string) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	base, exists, err := findKnowledgeBase(config.Metadata.Name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		payload := map[string]interface{}{
+			"name":        config.Metadata.Name,
+			"description": config.Spec.Description,
+			"data": map[string]interface{}{
+				"labels":      config.Metadata.Labels,
+				"annotations": config.Metadata.Annotations,
+			},
+		}
+		if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/knowledge/create", BASE_URL), payload, &base); err != nil {
+			return err
+		}
+	}
+
+	existingFiles := make(map[string]bool)
+	for _, f := range base.Files {
+		existingFiles[f.Meta.Name] = true
+	}
+
+	added, skipped := 0, 0
+	for _, source := range config.Spec.Sources {
+		files, skippedFiles, cleanup, err := resolveSource(source, manifestPath)
+		if err != nil {
+			cleanup()
+			return err
+		}
+		for _, file := range skippedFiles {
+			if file.Err != nil {
+				logf("Error converting file %s: %v\n", file.Name, file.Err)
+				continue
+			}
+			logf("Skipping file %s: %s\n", file.Name, file.Reason)
+		}
+		for _, file := range files {
+			if existingFiles[file.Filename] {
+				skipped++
+				continue
+			}
+			fileID, err := uploadFile(file.Path, file.Filename)
+			if err != nil {
+				logf("Error uploading file %s: %v\n", file.Path, err)
+				continue
+			}
+			payload := map[string]string{"file_id": fileID}
+			if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/knowledge/%s/file/add", BASE_URL, base.ID), payload, nil); err != nil {
+				logf("Error adding file %s to knowledge %s: %v\n", file.Path, config.Metadata.Name, err)
+				continue
+			}
+			existingFiles[file.Filename] = true
+			added++
+			logf("\rKnowledge files added: %d", added)
+		}
+		cleanup()
+	}
+	if added > 0 {
+		logf("\n")
+	}
+	if skipped > 0 {
+		logf("Knowledge %s: %d files already present\n", config.Metadata.Name, skipped)
+	}
+	return nil
+}
+
+func deleteKnowledge(config Knowledge) error {
+	base, exists, err := findKnowledgeBase(config.Metadata.Name)
+	if err != nil || !exists {
+		return err
+	}
+	return apiRequest("DELETE", fmt.Sprintf("%s/api/v1/knowledge/%s/delete", BASE_URL, base.ID), nil, nil)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type lfsPointer struct {
+	Path string
+	Oid  string
+	Size int64
+}
+
+func readLfsPointer(path string) (lfsPointer, bool) {
+	pointer := lfsPointer{Path: path}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > 1024 {
+		return pointer, false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || !bytes.HasPrefix(content, []byte("version https://git-lfs.github.com/spec/")) {
+		return pointer, false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "oid":
+			pointer.Oid = strings.TrimPrefix(value, "sha256:")
+		case "size":
+			pointer.Size, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return pointer, pointer.Oid != ""
+}
+
+func lfsBatchDownload(remote string, pointers []lfsPointer) error {
+	endpoint := strings.TrimSuffix(remote, "/")
+	if !strings.HasSuffix(endpoint, ".git") {
+		endpoint += ".git"
+	}
+	endpoint += "/info/lfs/objects/batch"
+
+	var objects []map[string]interface{}
+	for _, p := range pointers {
+		objects = append(objects, map[string]interface{}{"oid": p.Oid, "size": p.Size})
+	}
+	body, err := json.Marshal(map[string]interface{}{"operation": "download", "transfers": []string{"basic"}, "objects": objects})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("LFS batch request: %s - %s", resp.Status, string(respBody))
+	}
+
+	var batch struct {
+		Objects []struct {
+			Oid     string `json:"oid"`
+			Actions struct {
+				Download *struct {
+					Href   string            `json:"href"`
+					Header map[string]string `json:"header"`
+				} `json:"download"`
+			} `json:"actions"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"objects"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return err
+	}
+
+	for _, object := range batch.Objects {
+		if object.Error != nil || object.Actions.Download == nil {
+			logf("LFS object %s is not available\n", object.Oid)
+			continue
+		}
+		download := object.Actions.Download
+		req, err := http.NewRequest("GET", download.Href, nil)
+		if err != nil {
+			return err
+		}
+		for key, value := range download.Header {
+			registerSecret(value)
+			req.Header.Set(key, value)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("LFS download %s: %s", object.Oid, resp.Status)
+		}
+		content, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, p := range pointers {
+			if p.Oid == object.Oid {
+				if err := os.WriteFile(p.Path, content, 0644); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func fetchLfsObjects(repoDir, remote string, env []string, files []string) error {
+	var pointers []lfsPointer
+	var includes []string
+	for _, file := range files {
+		if pointer, ok := readLfsPointer(file); ok {
+			pointers = append(pointers, pointer)
+			relative, _ := filepath.Rel(repoDir, file)
+			includes = append(includes, filepath.ToSlash(relative))
+		}
+	}
+	if len(pointers) == 0 {
+		return nil
+	}
+
+	logf("Fetching %d Git LFS files\n", len(pointers))
+	if err := exec.Command("git", "lfs", "version").Run(); err == nil {
+		return runGitEnv(repoDir, env, "lfs", "pull", "--include", strings.Join(includes, ","))
+	}
+	if !isUrlSource(remote) {
+		return fmt.Errorf("%d files are Git LFS pointers; install git-lfs to fetch them", len(pointers))
+	}
+	return lfsBatchDownload(remote, pointers)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var mailHeaders = []string{"From", "To", "Cc", "Date", "Message-ID", "In-Reply-To"}
+
+func decodeMailBody(body io.Reader, encoding, charset string) string {
+	switch strings.ToLower(encoding) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	content, _ := io.ReadAll(body)
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		runes := make([]rune, len(content))
+		for i, b := range content {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return string(content)
+}
+
+func mailPartText(header map[string][]string, body io.Reader) (string, string, []string) {
+	get := func(key string) string {
+		if values := header[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	mediaType, params, err := mime.ParseMediaType(get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var plain, htmlText string
+		var attachments []string
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FileName() != "" {
+				attachments = append(attachments, part.FileName())
+				continue
+			}
+			p, h, nested := mailPartText(part.Header, part)
+			attachments = append(attachments, nested...)
+			if plain == "" {
+				plain = p
+			}
+			if htmlText == "" {
+				htmlText = h
+			}
+		}
+		return plain, htmlText, attachments
+	}
+
+	text := decodeMailBody(body, get("Content-Transfer-Encoding"), params["charset"])
+	switch mediaType {
+	case "text/plain":
+		return text, "", nil
+	case "text/html":
+		return "", text, nil
+	}
+	return "", "", nil
+}
+
+func mailDocument(raw []byte) (string, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return "", err
+	}
+	decoder := new(mime.WordDecoder)
+	decode := func(value string) string {
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			return decoded
+		}
+		return value
+	}
+
+	subject := decode(msg.Header.Get("Subject"))
+	var doc strings.Builder
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	fmt.Fprintf(&doc, "# %s\n\n", subject)
+	for _, key := range mailHeaders {
+		if value := msg.Header.Get(key); value != "" {
+			fmt.Fprintf(&doc, "%s: %s\n", key, decode(value))
+		}
+	}
+
+	plain, htmlText, attachments := mailPartText(msg.Header, msg.Body)
+	if plain == "" && htmlText != "" {
+		_, plain = htmlToMarkdown(htmlText, "")
+	}
+	if len(attachments) > 0 {
+		fmt.Fprintf(&doc, "Attachments: %s\n", strings.Join(attachments, ", "))
+	}
+	fmt.Fprintf(&doc, "\n%s\n", strings.TrimSpace(strings.ReplaceAll(plain, "\r\n", "\n")))
+	return doc.String(), nil
+}
+
+func splitMbox(content []byte) [][]byte {
+	var messages [][]byte
+	var current bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	started, previousBlank := false, true
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if bytes.HasPrefix(line, []byte("From ")) && previousBlank {
+			if started {
+				messages = append(messages, append([]byte(nil), current.Bytes()...))
+			}
+			current.Reset()
+			started, previousBlank = true, false
+			continue
+		}
+		if bytes.HasPrefix(line, []byte(">From ")) {
+			line = line[1:]
+		}
+		current.Write(line)
+		current.WriteByte('\n')
+		previousBlank = len(bytes.TrimSpace(line)) == 0
+	}
+	if started {
+		messages = append(messages, current.Bytes())
+	}
+	return messages
+}
+
+func extractMail(file sourceFile, tempDir string) ([]sourceFile, error) {
+	content, err := os.ReadFile(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	messages := [][]byte{content}
+	if strings.EqualFold(filepath.Ext(file.Filename), ".mbox") {
+		messages = splitMbox(content)
+	}
+
+	var files []sourceFile
+	for i, raw := range messages {
+		doc, err := mailDocument(raw)
+		if err != nil {
+			logf("Skipping message %d of %s: %v\n", i+1, file.Filename, err)
+			continue
+		}
+		name := base + ".md"
+		if len(messages) > 1 {
+			name = fmt.Sprintf("%s-%d.md", base, i+1)
+		}
+		target := filepath.Join(tempDir, fmt.Sprintf("%d-%s", i, safeArchiveName(name)))
+		if err := os.WriteFile(target, []byte(doc), 0644); err != nil {
+			return nil, err
+		}
+		files = append(files, sourceFile{Path: target, Filename: name, Tags: file.Tags})
+	}
+	return files, nil
+}
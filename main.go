@@ -3,12 +3,14 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -16,52 +18,187 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+type Metadata struct {
+	Name        string            `yaml:"name"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+	DependsOn   []string          `yaml:"dependsOn,omitempty"`
+}
+
 type Model struct {
-	Kind     string `yaml:"kind"`
-	Metadata struct {
-		Name string `yaml:"name"`
-	} `yaml:"metadata"`
-	Spec struct {
-		ID          string `yaml:"id"`
-		Name        string `yaml:"name"`
-		BaseModelID string `yaml:"base_model_id"`
-		Meta        struct {
-			ProfileImageURL    string `yaml:"profile_image_url"`
-			Description        string `yaml:"description"`
-			Capabilities       struct {
-				Vision bool `yaml:"vision"`
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		ID           string `yaml:"id,omitempty"`
+		Name         string `yaml:"name,omitempty"`
+		BaseModelID  string `yaml:"base_model_id"`
+		SystemPrompt string `yaml:"system_prompt,omitempty"`
+		IsActive     *bool  `yaml:"is_active,omitempty"`
+		Meta         struct {
+			ProfileImageURL string `yaml:"profile_image_url,omitempty"`
+			Description     string `yaml:"description,omitempty"`
+			Capabilities    struct {
+				Vision          bool  `yaml:"vision"`
+				WebSearch       *bool `yaml:"web_search,omitempty"`
+				ImageGeneration *bool `yaml:"image_generation,omitempty"`
+				CodeInterpreter *bool `yaml:"code_interpreter,omitempty"`
+				Citations       *bool `yaml:"citations,omitempty"`
 			} `yaml:"capabilities"`
-			SuggestionPrompts []string `yaml:"suggestion_prompts"`
-			Knowledge         []struct {
-				Tags string `yaml:"tags"`
-			} `yaml:"knowledge"`
+			Tags              []string           `yaml:"tags,omitempty"`
+			ToolIDs           []string           `yaml:"tool_ids,omitempty"`
+			FilterIDs         []string           `yaml:"filter_ids,omitempty"`
+			ActionIDs         []string           `yaml:"action_ids,omitempty"`
+			SuggestionPrompts []SuggestionPrompt `yaml:"suggestion_prompts,omitempty"`
+			Knowledge         []ModelKnowledge   `yaml:"knowledge,omitempty"`
 		} `yaml:"meta"`
-		Params map[string]string `yaml:"params,omitempty"`
+		Params        map[string]interface{} `yaml:"params,omitempty"`
+		AccessControl *AccessControl         `yaml:"accessControl,omitempty"`
 	} `yaml:"spec"`
 }
 
+type ModelKnowledge struct {
+	Tags      string `yaml:"tags,omitempty"`
+	Knowledge string `yaml:"knowledge,omitempty"`
+}
+
+type SuggestionPrompt struct {
+	Title   []string `yaml:"title,omitempty" json:"title,omitempty"`
+	Content string   `yaml:"content" json:"content"`
+}
+
+func (p *SuggestionPrompt) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		p.Content = value.Value
+		return nil
+	}
+
+	var prompt struct {
+		Title   yaml.Node `yaml:"title"`
+		Content string    `yaml:"content"`
+	}
+	if err := value.Decode(&prompt); err != nil {
+		return err
+	}
+	p.Content = prompt.Content
+	switch prompt.Title.Kind {
+	case yaml.ScalarNode:
+		p.Title = []string{prompt.Title.Value}
+	case yaml.SequenceNode:
+		if err := prompt.Title.Decode(&p.Title); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p SuggestionPrompt) MarshalYAML() (interface{}, error) {
+	if len(p.Title) == 0 {
+		return p.Content, nil
+	}
+	return struct {
+		Title   []string `yaml:"title,omitempty"`
+		Content string   `yaml:"content"`
+	}{p.Title, p.Content}, nil
+}
+
+type AccessGrant struct {
+	Groups []string `yaml:"groups,omitempty"`
+	Users  []string `yaml:"users,omitempty"`
+}
+
+type AccessControl struct {
+	Public bool        `yaml:"public,omitempty"`
+	Read   AccessGrant `yaml:"read,omitempty"`
+	Write  AccessGrant `yaml:"write,omitempty"`
+}
+
 type DocumentSource struct {
-	Source     string   `yaml:"source"`
-	Dir        []string `yaml:"dir,omitempty"`
-	Extensions []string `yaml:"extensions,omitempty"`
+	Source              string               `yaml:"source"`
+	Type                string               `yaml:"type,omitempty"`
+	Tags                []string             `yaml:"tags,omitempty"`
+	Ref                 string               `yaml:"ref,omitempty"`
+	Tarball             bool                 `yaml:"tarball,omitempty"`
+	FullClone           bool                 `yaml:"fullClone,omitempty"`
+	Dir                 []string             `yaml:"dir,omitempty"`
+	RespectGitignore    *bool                `yaml:"respectGitignore,omitempty"`
+	FollowSymlinks      string               `yaml:"followSymlinks,omitempty"`
+	MimeTypes           []string             `yaml:"mimeTypes,omitempty"`
+	MaxFileSize         string               `yaml:"maxFileSize,omitempty"`
+	Include             []string             `yaml:"include,omitempty"`
+	Exclude             []string             `yaml:"exclude,omitempty"`
+	Extensions          []string             `yaml:"extensions,omitempty"`
+	TagTemplate         string               `yaml:"tagTemplate,omitempty"`
+	TitleTemplate       string               `yaml:"titleTemplate,omitempty"`
+	Metadata            map[string]string    `yaml:"metadata,omitempty"`
+	FrontMatter         *FrontMatterConfig   `yaml:"frontMatter,omitempty"`
+	Encoding            string               `yaml:"encoding,omitempty"`
+	SkipUnknownEncoding bool                 `yaml:"skipUnknownEncoding,omitempty"`
+	CollectionPrefix    string               `yaml:"collectionPrefix,omitempty"`
+	SshKeyPath          string               `yaml:"sshKeyPath,omitempty"`
+	SshKnownHosts       string               `yaml:"sshKnownHosts,omitempty"`
+	HttpsTokenRef       string               `yaml:"httpsTokenRef,omitempty"`
+	IdentityFile        string               `yaml:"identityFile,omitempty"`
+	Username            string               `yaml:"username,omitempty"`
+	Password            string               `yaml:"password,omitempty"`
+	Token               string               `yaml:"token,omitempty"`
+	Headers             map[string]string    `yaml:"headers,omitempty"`
+	BearerTokenRef      string               `yaml:"bearerTokenRef,omitempty"`
+	Incremental         bool                 `yaml:"incremental,omitempty"`
+	TenantID            string               `yaml:"tenantId,omitempty"`
+	ClientID            string               `yaml:"clientId,omitempty"`
+	ClientSecret        string               `yaml:"clientSecret,omitempty"`
+	Query               string               `yaml:"query,omitempty"`
+	Items               []string             `yaml:"items,omitempty"`
+	Crawl               bool                 `yaml:"crawl,omitempty"`
+	MaxDepth            int                  `yaml:"maxDepth,omitempty"`
+	AllowDomains        []string             `yaml:"allowDomains,omitempty"`
+	ExcludePaths        []string             `yaml:"excludePaths,omitempty"`
+	Delay               string               `yaml:"delay,omitempty"`
+	SplitRows           int                  `yaml:"splitRows,omitempty"`
+	Categories          []int                `yaml:"categories,omitempty"`
+	Site                bool                 `yaml:"site,omitempty"`
+	ItemsJsonPath       string               `yaml:"itemsJSONPath,omitempty"`
+	NextPageJsonPath    string               `yaml:"nextPageJSONPath,omitempty"`
+	NextPageParam       string               `yaml:"nextPageParam,omitempty"`
+	IdJsonPath          string               `yaml:"idJSONPath,omitempty"`
+	Template            string               `yaml:"template,omitempty"`
+	PassthroughOffice   bool                 `yaml:"passthroughOffice,omitempty"`
+	ExtractText         bool                 `yaml:"extractText,omitempty"`
+	SplitOpenapi        bool                 `yaml:"splitOpenapi,omitempty"`
+	Ocr                 *OcrConfig           `yaml:"ocr,omitempty"`
+	Transcription       *TranscriptionConfig `yaml:"transcription,omitempty"`
 }
 
 type Documents struct {
-	Kind     string `yaml:"kind"`
-	Metadata struct {
-		Name string `yaml:"name"`
-	} `yaml:"metadata"`
-	Spec struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Tags    []string         `yaml:"tags,omitempty"`
 		Sources []DocumentSource `yaml:"sources"`
 	} `yaml:"spec"`
 }
 
+func (d Documents) sourceTags(source DocumentSource) []string {
+	tags := []string{d.Metadata.Name}
+	for _, tag := range append(append([]string{}, d.Spec.Tags...), source.Tags...) {
+		if tag != "" && !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 type Document struct {
 	CollectionName string `json:"collection_name"`
+	Name           string `json:"name"`
+	Filename       string `json:"filename"`
 	Content        struct {
 		Tags []struct {
 			Name string `json:"name"`
 		} `json:"tags"`
+		Labels      map[string]string `json:"labels,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+		Sha256      string            `json:"sha256,omitempty"`
 	} `json:"content"`
 }
 
@@ -70,36 +207,259 @@ var (
 	BASE_URL = "http://localhost:8081"
 )
 
-func parseYamlFile(filePath string) (interface{}, error) {
+func parseYamlFile(filePath string, opts manifestOptions) (manifest, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, err
+		return manifest{}, err
+	}
+
+	content, err = renderManifest(filePath, content, opts)
+	if err != nil {
+		return manifest{}, err
 	}
 
-	var doc Documents
-	if err = yaml.Unmarshal(content, &doc); err == nil {
-		if doc.Kind == "Documents" {
-			return doc, nil
+	content, err = applyPatches(content, opts)
+	if err != nil {
+		return manifest{}, fmt.Errorf("failed to apply overlay to %s: %v", filePath, err)
+	}
+
+	content, err = resolveSecrets(content, opts.ResolveSecrets)
+	if err != nil {
+		return manifest{}, fmt.Errorf("%s: %v", filePath, err)
+	}
+	return parseManifest(filePath, content)
+}
+
+func parseManifest(filePath string, content []byte) (manifest, error) {
+	m := manifest{Path: filePath}
+	err := yaml.Unmarshal(content, &m)
+	if err != nil {
+		return manifest{}, err
+	}
+
+	switch m.Kind {
+	case "Documents":
+		var doc Documents
+		err = yaml.Unmarshal(content, &doc)
+		m.Config = doc
+	case "Model":
+		var model Model
+		err = yaml.Unmarshal(content, &model)
+		m.Config = model
+	case "Prompt":
+		var prompt Prompt
+		err = yaml.Unmarshal(content, &prompt)
+		m.Config = prompt
+	case "Tool":
+		var tool Tool
+		err = yaml.Unmarshal(content, &tool)
+		m.Config = tool
+	case "Function":
+		var function Function
+		err = yaml.Unmarshal(content, &function)
+		m.Config = function
+	case "Knowledge":
+		var knowledge Knowledge
+		err = yaml.Unmarshal(content, &knowledge)
+		m.Config = knowledge
+	case "User":
+		var user User
+		err = yaml.Unmarshal(content, &user)
+		m.Config = user
+	case "Group":
+		var group Group
+		err = yaml.Unmarshal(content, &group)
+		m.Config = group
+	case "Config":
+		var config InstanceConfig
+		err = yaml.Unmarshal(content, &config)
+		m.Config = config
+	case "RAGConfig":
+		var config RAGConfig
+		err = yaml.Unmarshal(content, &config)
+		m.Config = config
+	case "WebSearchConfig":
+		var config WebSearchConfig
+		err = yaml.Unmarshal(content, &config)
+		m.Config = config
+	case "ImageGenerationConfig":
+		var config ImageGenerationConfig
+		err = yaml.Unmarshal(content, &config)
+		m.Config = config
+	case "AudioConfig":
+		var config AudioConfig
+		err = yaml.Unmarshal(content, &config)
+		m.Config = config
+	case "Banner":
+		var banner Banner
+		err = yaml.Unmarshal(content, &banner)
+		m.Config = banner
+	case "Pipeline":
+		var pipeline Pipeline
+		err = yaml.Unmarshal(content, &pipeline)
+		m.Config = pipeline
+	case "ArenaModel":
+		var arena ArenaModel
+		err = yaml.Unmarshal(content, &arena)
+		m.Config = arena
+	case "Folder":
+		var folder Folder
+		err = yaml.Unmarshal(content, &folder)
+		m.Config = folder
+	case "ChatArchive":
+		var archive ChatArchive
+		err = yaml.Unmarshal(content, &archive)
+		m.Config = archive
+	case "Connection":
+		var connection Connection
+		err = yaml.Unmarshal(content, &connection)
+		m.Config = connection
+	case "Webhook":
+		var webhook Webhook
+		err = yaml.Unmarshal(content, &webhook)
+		m.Config = webhook
+	case "AuthProvider":
+		var provider AuthProvider
+		err = yaml.Unmarshal(content, &provider)
+		m.Config = provider
+	default:
+		return manifest{}, fmt.Errorf("unknown kind in file %s", filePath)
+	}
+	if err != nil {
+		return manifest{}, err
+	}
+	return m, nil
+}
+
+func runGit(dir string, args ...string) error {
+	return runGitEnv(dir, nil, args...)
+}
+
+func useGoGit() bool {
+	return goGitAvailable && os.Getenv("OICTL_GIT_BINARY") == ""
+}
+
+func runGitEnv(dir string, env []string, args ...string) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git %s: the git executable was not found in PATH; install git or unset OICTL_GIT_BINARY to use go-git", args[0])
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func cloneGitRepo(repoUrl, localPath string) error {
+	if useGoGit() {
+		return goGitClone(DocumentSource{Source: repoUrl, FullClone: true}, localPath)
+	}
+	return runGit("", "clone", "--quiet", "--", repoUrl, localPath)
+}
+
+func sparsePatterns(dirs []string) []string {
+	var patterns []string
+	for _, dir := range dirs {
+		dir = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(dir)), "/")
+		if dir == "." || dir == "" {
+			return nil
 		}
+		patterns = append(patterns, "/"+dir)
 	}
+	return patterns
+}
 
-	var model Model
-	if err = yaml.Unmarshal(content, &model); err == nil {
-		if model.Kind == "Model" {
-			return model, nil
+func cloneGitSource(source DocumentSource, localPath string, env []string) error {
+	patterns := sparsePatterns(source.Dir)
+	var sparseArgs []string
+	if len(patterns) > 0 {
+		sparseArgs = []string{"--filter=blob:none", "--no-checkout"}
+	}
+	checkout := func(shallow bool) error {
+		if len(patterns) == 0 {
+			if shallow {
+				return nil
+			}
+			return checkoutGitRef(localPath, source.Ref, env)
+		}
+		if err := runGitEnv(localPath, env, append([]string{"sparse-checkout", "set", "--no-cone"}, patterns...)...); err != nil {
+			return err
+		}
+		if shallow || source.Ref == "" {
+			return runGitEnv(localPath, env, "checkout", "--quiet")
+		}
+		return checkoutGitRef(localPath, source.Ref, env)
+	}
+
+	if !source.FullClone {
+		args := append([]string{"clone", "--quiet", "--depth", "1"}, sparseArgs...)
+		if source.Ref != "" {
+			args = append(args, "--branch", source.Ref)
+		}
+		if err := runGitEnv("", env, append(args, "--", source.Source, localPath)...); err == nil {
+			return checkout(true)
 		}
+		os.RemoveAll(localPath)
+		args = append([]string{"clone", "--quiet", "--filter=blob:none"}, sparseArgs...)
+		if err := runGitEnv("", env, append(args, "--", source.Source, localPath)...); err != nil {
+			return err
+		}
+		return checkout(false)
+	}
+	if err := runGitEnv("", env, append(append([]string{"clone", "--quiet"}, sparseArgs...), "--", source.Source, localPath)...); err != nil {
+		return err
 	}
+	return checkout(false)
+}
 
-	return nil, fmt.Errorf("unknown kind in file %s", filePath)
+func checkoutRepoRef(localPath, ref string) error {
+	if useGoGit() {
+		return goGitCheckout(localPath, ref, nil)
+	}
+	return checkoutGitRef(localPath, ref, nil)
 }
 
-func cloneGitRepo(repoUrl, localPath string) error {
-	cmd := exec.Command("git", "clone", repoUrl, localPath)
-	return cmd.Run()
+func checkoutGitRef(localPath, ref string, env []string) error {
+	if ref == "" {
+		return nil
+	}
+	if err := runGitEnv(localPath, env, "checkout", "--quiet", ref); err == nil {
+		return nil
+	}
+	if err := runGitEnv(localPath, env, "fetch", "--quiet", "origin", ref); err != nil {
+		return err
+	}
+	return runGitEnv(localPath, env, "checkout", "--quiet", "FETCH_HEAD")
 }
 
-func fetchUrlContent(url string) (string, error) {
-	resp, err := http.Get(url)
+func urlHeaders(source DocumentSource) (map[string]string, error) {
+	headers := make(map[string]string)
+	for key, value := range source.Headers {
+		registerSecret(value)
+		headers[key] = value
+	}
+	if source.BearerTokenRef != "" {
+		token, err := resolveSecretRef(source.BearerTokenRef)
+		if err != nil {
+			return nil, err
+		}
+		headers["Authorization"] = fmt.Sprintf("Bearer %s", token)
+	}
+	return headers, nil
+}
+
+func fetchUrlContent(url string, headers map[string]string) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -117,18 +477,93 @@ func fetchUrlContent(url string) (string, error) {
 	return string(body), nil
 }
 
-func traverseDirectory(dir string, extensions []string) ([]string, error) {
+func traverseDirectory(dir string, source DocumentSource, ignore *ignoreMatcher) ([]string, error) {
+	policy := source.FollowSymlinks
+	switch policy {
+	case "":
+		policy = "insideRoot"
+	case "never", "insideRoot", "always":
+	default:
+		return nil, fmt.Errorf("invalid followSymlinks %s, expected never, insideRoot or always", source.FollowSymlinks)
+	}
+	realRoot, err := filepath.EvalSymlinks(ignore.root)
+	if err != nil {
+		return nil, err
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type linkedDir struct {
+		path  string
+		depth int
+	}
 	var files []string
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	var linkedDirs []linkedDir
+	visited := map[string]bool{realDir: true}
+	var walk func(dir string, depth int) error
+	walk = func(dir string, depth int) error {
+		ignore.load(dir)
+		entries, err := os.ReadDir(dir)
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && (len(extensions) == 0 || hasExtension(path, extensions)) {
-			files = append(files, path)
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			isDir := entry.IsDir()
+			realPath := ""
+			if entry.Type()&os.ModeSymlink != 0 {
+				if policy == "never" {
+					continue
+				}
+				if realPath, err = filepath.EvalSymlinks(path); err != nil {
+					continue
+				}
+				if rel, err := filepath.Rel(realRoot, realPath); policy == "insideRoot" && (err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+					continue
+				}
+				info, err := os.Stat(realPath)
+				if err != nil {
+					continue
+				}
+				isDir = info.IsDir()
+			}
+			if ignore.ignored(path, isDir) {
+				continue
+			}
+			if isDir && source.MaxDepth > 0 && depth+1 >= source.MaxDepth {
+				continue
+			} else if isDir && realPath != "" {
+				linkedDirs = append(linkedDirs, linkedDir{path, depth + 1})
+			} else if isDir {
+				realPath, _ = filepath.EvalSymlinks(path)
+				visited[realPath] = true
+				if err := walk(path, depth+1); err != nil {
+					return err
+				}
+			} else if len(source.Extensions) == 0 || hasExtension(path, source.Extensions) {
+				files = append(files, path)
+			}
 		}
 		return nil
-	})
-	return files, err
+	}
+	if err := walk(dir, 0); err != nil {
+		return nil, err
+	}
+	for len(linkedDirs) > 0 {
+		linked := linkedDirs[0]
+		linkedDirs = linkedDirs[1:]
+		realPath, _ := filepath.EvalSymlinks(linked.path)
+		if visited[realPath] {
+			continue
+		}
+		visited[realPath] = true
+		if err := walk(linked.path, linked.depth); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
 }
 
 func hasExtension(filePath string, extensions []string) bool {
@@ -140,132 +575,431 @@ func hasExtension(filePath string, extensions []string) bool {
 	return false
 }
 
-func handleGitSource(source string, dirs, extensions []string) ([]string, string, error) {
+func handleGitSource(source DocumentSource) ([]string, string, error) {
 	workingDir, _ := os.Getwd()
 	tempDir := filepath.Join(workingDir, fmt.Sprintf("temp_git_%s", uuid.New().String()))
-	err := cloneGitRepo(source, tempDir)
+	_, lookErr := exec.LookPath("git")
+	if source.Tarball || (lookErr != nil && !useGoGit()) {
+		if project, ok := forgeProject(source.Source); ok {
+			logf("Downloading the archive of %s\n", strings.Trim(project.Path, "/"))
+			if err := downloadForgeArchive(source, project, tempDir); err != nil {
+				os.RemoveAll(tempDir)
+				return nil, "", err
+			}
+			sources, err := collectRepoFiles(tempDir, source.Dir, source)
+			if err != nil {
+				os.RemoveAll(tempDir)
+				return nil, "", err
+			}
+			return sources, tempDir, nil
+		}
+		if source.Tarball {
+			return nil, "", fmt.Errorf("tarball is only supported for https://github.com and https://gitlab.com sources, got %s", source.Source)
+		}
+	}
+	env, remote, err := gitAuthEnv(source)
 	if err != nil {
 		return nil, "", err
 	}
+	clone := func() error { return cloneGitSource(source, tempDir, env) }
+	if useGoGit() {
+		clone = func() error { return goGitClone(source, tempDir) }
+	}
+	if err := clone(); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", err
+	}
 
+	sources, err := collectRepoFiles(tempDir, source.Dir, source)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", err
+	}
+	if err := fetchLfsObjects(tempDir, remote, env, sources); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", err
+	}
+	return sources, tempDir, nil
+}
+
+func collectRepoFiles(repoDir string, dirs []string, source DocumentSource) ([]string, error) {
+	extensions := source.Extensions
+	ignore, err := sourceIgnoreMatcher(repoDir, source)
+	if err != nil {
+		return nil, err
+	}
 	var sources []string
 	for _, dir := range dirs {
-		fullPath := filepath.Join(tempDir, dir)
+		fullPath := filepath.Join(repoDir, dir)
 		stat, err := os.Stat(fullPath)
 		if err != nil {
-			return nil, "", err
+			return nil, err
 		}
 		if stat.IsDir() {
-			files, err := traverseDirectory(fullPath, extensions)
+			files, err := traverseDirectory(fullPath, source, ignore)
 			if err != nil {
-				return nil, "", err
+				return nil, err
 			}
 			sources = append(sources, files...)
-		} else if stat.Mode().IsRegular() && hasExtension(fullPath, extensions) {
+		} else if stat.Mode().IsRegular() && hasExtension(fullPath, extensions) && !ignore.ignored(fullPath, false) {
 			sources = append(sources, fullPath)
 		}
 	}
+	return sources, nil
+}
 
-	return sources, tempDir, nil
+type sourceFile struct {
+	Path     string
+	Filename string
+	Origin   string
+	Root     string
+	Title    string
+	Tags     []string
+	Metadata map[string]string
 }
 
-func uploadDocument(file, baseUrl, tag, originalFilename string) error {
-	ragDocUrl := fmt.Sprintf("%s/rag/api/v1/doc", baseUrl)
-	documentsUrl := fmt.Sprintf("%s/api/v1/documents/create", baseUrl)
+func isGitSource(source string) bool {
+	return strings.HasPrefix(source, "git@") || strings.HasSuffix(source, ".git")
+}
 
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("file", originalFilename)
-	if err != nil {
-		return err
+func isUrlSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+func isLocalSource(source string) bool {
+	return !isGitSource(source) && !isGithubSource(source) && !isWikiSource(source) && !strings.Contains(source, "://")
+}
+
+func localSourceFiles(paths []string) []sourceFile {
+	var files []sourceFile
+	for _, path := range paths {
+		files = append(files, sourceFile{Path: path, Filename: filepath.Base(path)})
 	}
-	fileContent, err := os.Open(file)
-	if err != nil {
-		return err
+	return files
+}
+
+func removeTempDir(tempDir string) func() {
+	return func() {
+		if err := os.RemoveAll(tempDir); err != nil {
+			logf("\nFailed to remove temporary directory: %s\n", tempDir)
+		} else {
+			logf("\nTemporary directory removed: %s\n", tempDir)
+		}
 	}
-	defer fileContent.Close()
+}
 
-	_, err = io.Copy(part, fileContent)
-	if err != nil {
-		return err
+func fetchSource(source DocumentSource, manifestPath string) ([]sourceFile, func(), error) {
+	cleanup := func() {}
+
+	if isWikiSource(source.Source) {
+		files, tempDir, err := handleWikiSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
 	}
-	writer.Close()
 
-	req, err := http.NewRequest("POST", ragDocUrl, body)
-	if err != nil {
-		return err
+	if isGitSource(source.Source) {
+		files, tempDir, err := handleGitSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", TOKEN))
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	if isGithubSource(source.Source) {
+		files, tempDir, err := handleGithubSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return files, removeTempDir(tempDir), nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload file %s: %s - %s", file, resp.Status, string(respBody))
+	if isOciSource(source.Source) {
+		files, tempDir, err := handleOciSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
 	}
 
-	var responseBody map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&responseBody)
+	if isGcsSource(source.Source) {
+		files, tempDir, err := handleGcsSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
+	}
+
+	switch source.Type {
+	case "":
+	case "webdav":
+		files, tempDir, err := handleWebdavSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
+	case "sharepoint":
+		files, tempDir, err := handleSharepointSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
+	case "gitlab":
+		files, tempDir, err := handleGitlabSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
+	case "discourse":
+		files, tempDir, err := handleDiscourseSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return files, removeTempDir(tempDir), nil
+	case "api":
+		files, tempDir, err := handleApiSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
+	case "jira":
+		files, tempDir, err := handleJiraSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
+	case "dropbox":
+		files, tempDir, commit, err := handleDropboxSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		remove := removeTempDir(tempDir)
+		return localSourceFiles(files), func() { commit(); remove() }, nil
+	default:
+		return nil, cleanup, fmt.Errorf("unknown source type %s for %s", source.Type, source.Source)
+	}
+
+	if isSftpSource(source.Source) {
+		files, tempDir, err := handleSftpSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
+	}
+
+	if isArchiveSource(source.Source) {
+		files, tempDir, err := handleArchiveSource(source, manifestPath)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), removeTempDir(tempDir), nil
+	}
+
+	if source.Site && isUrlSource(source.Source) {
+		files, tempDir, err := handleSiteUrlSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return files, removeTempDir(tempDir), nil
+	}
+
+	if source.Crawl {
+		files, tempDir, err := handleCrawlSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return files, removeTempDir(tempDir), nil
+	}
+
+	if isSitemapSource(source.Source) {
+		files, tempDir, err := handleSitemapSource(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return files, removeTempDir(tempDir), nil
+	}
+
+	if isUrlSource(source.Source) {
+		headers, err := urlHeaders(source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		content, err := fetchUrlContent(source.Source, headers)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		filename := path.Base(strings.TrimSuffix(strings.SplitN(strings.SplitN(source.Source, "?", 2)[0], "#", 2)[0], "/"))
+		if ext := strings.ToLower(path.Ext(filename)); ext == "" || ext == ".html" || ext == ".htm" {
+			filename = pageFilename(source.Source)
+			content = pageDocument(source.Source, content)
+		}
+		tempFile := filepath.Join(os.TempDir(), fmt.Sprintf("temp_url_%s%s", uuid.New().String(), path.Ext(filename)))
+		if err := os.WriteFile(tempFile, []byte(content), 0644); err != nil {
+			return nil, cleanup, err
+		}
+		cleanup = func() { os.Remove(tempFile) }
+		return []sourceFile{{Path: tempFile, Filename: filename}}, cleanup, nil
+	}
+
+	resolvedPath, _ := filepath.Abs(filepath.Join(filepath.Dir(manifestPath), source.Source))
+	stat, err := os.Stat(resolvedPath)
 	if err != nil {
-		return err
+		return nil, cleanup, nil
 	}
+	if stat.IsDir() {
+		ignore, err := localIgnoreMatcher(resolvedPath, source)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		files, err := traverseDirectory(resolvedPath, source, ignore)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		return localSourceFiles(files), cleanup, nil
+	}
+	if stat.Mode().IsRegular() {
+		return localSourceFiles([]string{resolvedPath}), cleanup, nil
+	}
+	return nil, cleanup, nil
+}
+
+type uploadedDocument struct {
+	Collection string
+	Name       string
+}
+
+type documentOptions struct {
+	Title            string
+	CollectionPrefix string
+	Replace          string
+	Metadata         map[string]string
+}
+
+func uploadDocument(file, baseUrl string, tags []string, originalFilename string, metadata Metadata) error {
+	_, err := createDocument(file, baseUrl, tags, originalFilename, metadata, documentOptions{})
+	return err
+}
+
+func createDocument(file, baseUrl string, tags []string, originalFilename string, metadata Metadata, opts documentOptions) (uploadedDocument, error) {
+	ragDocUrl := fmt.Sprintf("%s/rag/api/v1/doc", baseUrl)
+	documentsUrl := fmt.Sprintf("%s/api/v1/documents/create", baseUrl)
+
+	hash, hashErr := fileSha256(file)
+	fields := make(map[string]string)
+	if opts.CollectionPrefix != "" && hashErr == nil {
+		fields["collection_name"] = collectionName(opts.CollectionPrefix, hash)
+	}
+
+	var collectionName, filename string
+	if existing, ok := uploadedContent.collection(hash); ok && hashErr == nil && reuseUploads.Load() && (fields["collection_name"] == "" || fields["collection_name"] == existing) {
+		collectionName, filename = existing, originalFilename
+	} else {
+		resp, err := postFile(ragDocUrl, file, originalFilename, fields)
+		if err != nil {
+			return uploadedDocument{}, err
+		}
+		defer resp.Body.Close()
 
-	collectionName := responseBody["collection_name"].(string)
-	filename := responseBody["filename"].(string)
+		if resp.StatusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(resp.Body)
+			return uploadedDocument{}, fmt.Errorf("failed to upload file %s: %s - %s", file, resp.Status, string(respBody))
+		}
+
+		var responseBody map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&responseBody)
+		if err != nil {
+			return uploadedDocument{}, err
+		}
 
+		collectionName = responseBody["collection_name"].(string)
+		filename = responseBody["filename"].(string)
+		if hashErr == nil {
+			uploadedContent.add(hash, collectionName)
+		}
+	}
+
+	var tagList []map[string]string
+	for _, tag := range tags {
+		tagList = append(tagList, map[string]string{"name": tag})
+	}
 	content := map[string]interface{}{
-		"tags": []map[string]string{{"name": tag}},
+		"tags": tagList,
+	}
+	if hashErr == nil {
+		content["sha256"] = hash
+	}
+	if len(metadata.Labels) > 0 {
+		content["labels"] = metadata.Labels
+	}
+	if len(metadata.Annotations) > 0 {
+		content["annotations"] = metadata.Annotations
+	}
+	if len(opts.Metadata) > 0 {
+		content["metadata"] = opts.Metadata
 	}
 	contentJSON, err := json.Marshal(content)
 	if err != nil {
-		return err
+		return uploadedDocument{}, err
 	}
 
+	title := filename
+	if opts.Title != "" {
+		title = opts.Title
+	}
+	name := filename
+	if opts.Replace == filename {
+		name = fmt.Sprintf("%s~%s", filename, uuid.New().String()[:8])
+	}
 	documentPayload := map[string]interface{}{
 		"collection_name": collectionName,
 		"filename":        filename,
-		"name":            filename,
-		"title":           filename,
+		"name":            name,
+		"title":           title,
 		"content":         string(contentJSON),
 	}
 
 	documentBody, err := json.Marshal(documentPayload)
 	if err != nil {
-		return err
+		return uploadedDocument{}, err
 	}
 
 	docReq, err := http.NewRequest("POST", documentsUrl, bytes.NewReader(documentBody))
 	if err != nil {
-		return err
+		return uploadedDocument{}, err
 	}
 	docReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", TOKEN))
 	docReq.Header.Set("Accept", "application/json")
 	docReq.Header.Set("Content-Type", "application/json")
 
-	docResp, err := client.Do(docReq)
+	docResp, err := httpClient.Do(docReq)
 	if err != nil {
-		return err
+		return uploadedDocument{}, err
 	}
 	defer docResp.Body.Close()
 
 	if docResp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(docResp.Body)
-		fmt.Printf("An error occurred: failed to create document entry for file %s: %s - %s\n", file, docResp.Status, string(respBody))
-		return nil
+		return uploadedDocument{}, fmt.Errorf("failed to create document entry for file %s: %s - %s", file, docResp.Status, string(respBody))
 	}
 
-	return nil
+	if opts.Replace != "" {
+		deleteUrl := fmt.Sprintf("%s/api/v1/documents/doc/delete?name=%s", baseUrl, url.QueryEscape(opts.Replace))
+		if err := apiRequest("DELETE", deleteUrl, nil, nil); err != nil {
+			return uploadedDocument{Collection: collectionName, Name: name}, fmt.Errorf("failed to replace document %s, the new content was uploaded as %s: %v", opts.Replace, name, err)
+		}
+	}
+	if name != filename {
+		updateUrl := fmt.Sprintf("%s/api/v1/documents/doc/update?name=%s", baseUrl, url.QueryEscape(name))
+		if err := apiRequest("POST", updateUrl, map[string]interface{}{"name": filename, "title": title}, nil); err != nil {
+			return uploadedDocument{Collection: collectionName, Name: name}, fmt.Errorf("failed to rename document %s to %s: %v", name, filename, err)
+		}
+	}
+	return uploadedDocument{Collection: collectionName, Name: filename}, nil
 }
 
 func getDocs(token string) ([]Document, error) {
-	client := &http.Client{}
+	client := httpClient
 	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/documents/", BASE_URL), nil)
 	if err != nil {
 		return nil, err
@@ -315,53 +1049,126 @@ func fetchCollectionNamesForTags(tags []string, token string) (map[string][]stri
 }
 
 func processModel(config Model) error {
+	return saveModel(config, fmt.Sprintf("%s/api/v1/models/add", BASE_URL))
+}
+
+func updateModel(config Model) error {
+	return saveModel(config, fmt.Sprintf("%s/api/v1/models/update?id=%s", BASE_URL, url.QueryEscape(config.Metadata.Name)))
+}
+
+func saveModel(config Model, baseUrl string) error {
 	if TOKEN == "" {
 		return fmt.Errorf("OI_TOKEN environment variable is not set")
 	}
 
-	baseUrl := fmt.Sprintf("%s/api/v1/models/add", BASE_URL)
-
 	if config.Spec.Params == nil {
-		config.Spec.Params = make(map[string]string)
+		config.Spec.Params = make(map[string]interface{})
 	}
 
 	var tags []string
 	for _, knowledge := range config.Spec.Meta.Knowledge {
-		tags = append(tags, knowledge.Tags)
+		if knowledge.Tags != "" {
+			tags = append(tags, knowledge.Tags)
+		}
 	}
 
-	collections, err := fetchCollectionNamesForTags(tags, TOKEN)
-	if err != nil {
-		return err
+	collections := make(map[string][]string)
+	if len(tags) > 0 {
+		var err error
+		collections, err = fetchCollectionNamesForTags(tags, TOKEN)
+		if err != nil {
+			return err
+		}
 	}
 
 	var knowledgeEntries []map[string]interface{}
 	for _, knowledge := range config.Spec.Meta.Knowledge {
-		if len(collections[knowledge.Tags]) > 0 {
-			knowledgeEntry := map[string]interface{}{
-				"name":             knowledge.Tags,
-				"type":             "collection",
-				"collection_names": collections[knowledge.Tags],
+		if knowledge.Knowledge != "" {
+			base, exists, err := findKnowledgeBase(knowledge.Knowledge)
+			if err != nil {
+				return err
 			}
-			knowledgeEntries = append(knowledgeEntries, knowledgeEntry)
+			if !exists {
+				logf("Warning: model %s references knowledge %s which does not exist\n", config.Metadata.Name, knowledge.Knowledge)
+				continue
+			}
+			knowledgeEntries = append(knowledgeEntries, map[string]interface{}{
+				"id":          base.ID,
+				"name":        base.Name,
+				"description": base.Description,
+				"type":        "collection",
+			})
+			continue
+		}
+		if len(collections[knowledge.Tags]) == 0 {
+			logf("Warning: model %s references tag %s which has no document collections\n", config.Metadata.Name, knowledge.Tags)
+			continue
+		}
+		knowledgeEntry := map[string]interface{}{
+			"name":             knowledge.Tags,
+			"type":             "collection",
+			"collection_names": collections[knowledge.Tags],
+		}
+		knowledgeEntries = append(knowledgeEntries, knowledgeEntry)
+	}
+
+	if config.Spec.SystemPrompt != "" {
+		config.Spec.Params["system"] = config.Spec.SystemPrompt
+	}
+
+	capabilities := map[string]interface{}{
+		"vision": config.Spec.Meta.Capabilities.Vision,
+	}
+	optionalCapabilities := map[string]*bool{
+		"web_search":       config.Spec.Meta.Capabilities.WebSearch,
+		"image_generation": config.Spec.Meta.Capabilities.ImageGeneration,
+		"code_interpreter": config.Spec.Meta.Capabilities.CodeInterpreter,
+		"citations":        config.Spec.Meta.Capabilities.Citations,
+	}
+	for name, enabled := range optionalCapabilities {
+		if enabled != nil {
+			capabilities[name] = *enabled
 		}
 	}
 
+	var modelTags []map[string]string
+	for _, tag := range config.Spec.Meta.Tags {
+		modelTags = append(modelTags, map[string]string{"name": tag})
+	}
+
+	name := config.Metadata.Name
+	if config.Spec.Name != "" {
+		name = config.Spec.Name
+	}
 	modelPayload := map[string]interface{}{
 		"id":            config.Metadata.Name,
-		"name":          config.Metadata.Name,
+		"name":          name,
 		"base_model_id": config.Spec.BaseModelID,
 		"meta": map[string]interface{}{
-			"profile_image_url": config.Spec.Meta.ProfileImageURL,
-			"description":       config.Spec.Meta.Description,
-			"capabilities": map[string]interface{}{
-				"vision": config.Spec.Meta.Capabilities.Vision,
-			},
+			"profile_image_url":  config.Spec.Meta.ProfileImageURL,
+			"description":        config.Spec.Meta.Description,
+			"capabilities":       capabilities,
+			"tags":               modelTags,
 			"suggestion_prompts": config.Spec.Meta.SuggestionPrompts,
-			"knowledge":         knowledgeEntries,
+			"knowledge":          knowledgeEntries,
+			"toolIds":            config.Spec.Meta.ToolIDs,
+			"filterIds":          config.Spec.Meta.FilterIDs,
+			"actionIds":          config.Spec.Meta.ActionIDs,
+			"labels":             config.Metadata.Labels,
+			"annotations":        config.Metadata.Annotations,
 		},
 		"params": config.Spec.Params,
 	}
+	if config.Spec.IsActive != nil {
+		modelPayload["is_active"] = *config.Spec.IsActive
+	}
+	if config.Spec.AccessControl != nil {
+		accessControl, err := resolveAccessControl(*config.Spec.AccessControl)
+		if err != nil {
+			return err
+		}
+		modelPayload["access_control"] = accessControl
+	}
 
 	body, err := json.Marshal(modelPayload)
 	if err != nil {
@@ -375,7 +1182,7 @@ func processModel(config Model) error {
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", TOKEN))
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := httpClient
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -406,131 +1213,296 @@ func processDirectory(directory string) ([]string, error) {
 	return paths, nil
 }
 
-func handleOictl(paths []string) error {
-	documentCount := 0
+type applyOptions struct {
+	PullBaseModels bool
+	Parallel       int
+	ForceUpload    bool
+	StatePath      string
+	OnConflict     string
+	PruneDocuments bool
+}
+
+func handleOictl(paths []string, opts manifestOptions, apply applyOptions) error {
+	var progress uploadProgress
 	modelCount := 0
 
-	for _, filePath := range paths {
-		config, err := parseYamlFile(filePath)
-		if err != nil {
-			fmt.Printf("Skipped due to unknown kind in file %s\n", filePath)
-			continue
+	manifests, err := sortManifests(loadManifests(paths, opts))
+	if err != nil {
+		return err
+	}
+	plannedMemberships = planMemberships(manifests)
+
+	var webhooks []Webhook
+	for _, m := range manifests {
+		if webhook, ok := m.Config.(Webhook); ok {
+			webhooks = append(webhooks, webhook)
 		}
+	}
+	report := applyReport{Server: BASE_URL}
+
+	statePath := apply.StatePath
+	if statePath == "" {
+		statePath = defaultStatePath(BASE_URL)
+	}
+	state, err := loadState(statePath, BASE_URL)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range manifests {
+		filePath := m.Path
 
-		switch c := config.(type) {
+		switch c := m.Config.(type) {
+		case Webhook:
+			continue
 		case Documents:
 			tag := c.Metadata.Name
+			hashes, names, err := documentHashes(tag)
+			action := "updated"
+			if err != nil {
+				logf("Could not list the documents of %s, uploading all files: %v\n", tag, err)
+				action = ""
+			} else if len(names) == 0 {
+				action = "created"
+			}
 			for _, source := range c.Spec.Sources {
-				if strings.HasPrefix(source.Source, "git@") || strings.HasSuffix(source.Source, ".git") {
-					sources, tempDir, err := handleGitSource(source.Source, source.Dir, source.Extensions)
-					if err != nil {
-						return err
-					}
-					for _, file := range sources {
-						err := uploadDocument(file, BASE_URL, tag, filepath.Base(file))
-						if err != nil {
-							fmt.Printf("Error uploading document %s: %v\n", file, err)
-							continue
-						}
-						documentCount++
-						fmt.Printf("\rDocuments loaded: %d", documentCount)
-					}
-					err = os.RemoveAll(tempDir)
-					if err != nil {
-						fmt.Printf("\nFailed to remove temporary directory: %s\n", tempDir)
-					} else {
-						fmt.Printf("\nTemporary directory removed: %s\n", tempDir)
-					}
-				} else if strings.HasPrefix(source.Source, "http://") || strings.HasPrefix(source.Source, "https://") {
-					content, err := fetchUrlContent(source.Source)
-					if err != nil {
-						return err
+				files, skipped, cleanup, err := resolveSource(source, filePath)
+				if err != nil {
+					cleanup()
+					report.failed(m.Kind, m.Metadata.Name, err)
+					report.Aborted = redact(err.Error())
+					notifyWebhooks(webhooks, report)
+					return err
+				}
+				for _, file := range skipped {
+					if file.Root != "" {
+						state.markOrigin(tag, source.Source, file.Origin)
 					}
-					tempFile := filepath.Join(os.TempDir(), fmt.Sprintf("temp_url_%s.yaml", uuid.New().String()))
-					err = os.WriteFile(tempFile, []byte(content), 0644)
-					if err != nil {
-						return err
+					if file.Err != nil {
+						logf("\nError converting document %s: %v\n", file.Name, file.Err)
+						report.failed("Document", file.Name, file.Err)
+						continue
 					}
-					err = uploadDocument(tempFile, BASE_URL, tag, source.Source)
+					report.Skipped = append(report.Skipped, file)
+				}
+				state.markPresent(tag, source.Source, presentRoot(source, filePath, files, skipped))
+				files, entries, unchanged := pendingFiles(files, tag, source.Source, hashes, state, apply.ForceUpload)
+				report.Unchanged += unchanged
+				replace := make(map[string]string)
+				if apply.OnConflict != "duplicate" {
+					var conflicts []skippedFile
+					files, entries, conflicts = resolveConflicts(files, entries, names, apply.OnConflict, replace, state)
+					report.Skipped = append(report.Skipped, conflicts...)
+				}
+				docs, errs := uploadSourceFiles(files, c.sourceTags(source), c.Metadata, source, replace, apply.Parallel, &progress)
+				for i, err := range errs {
 					if err != nil {
-						fmt.Printf("Error uploading document %s: %v\n", tempFile, err)
+						logf("\nError uploading document %s: %v\n", files[i].Path, err)
+						report.failed("Document", files[i].Filename, err)
+						state.markSeen(entries[i].key())
 						continue
 					}
-					documentCount++
-					fmt.Printf("\rDocuments loaded: %d", documentCount)
-				} else {
-					resolvedPath, _ := filepath.Abs(filepath.Join(filepath.Dir(filePath), source.Source))
-					if _, err := os.Stat(resolvedPath); err == nil {
-						if stat, err := os.Stat(resolvedPath); err == nil && stat.IsDir() {
-							files, err := traverseDirectory(resolvedPath, source.Extensions)
-							if err != nil {
-								return err
-							}
-							for _, file := range files {
-								err := uploadDocument(file, BASE_URL, tag, filepath.Base(file))
-								if err != nil {
-									fmt.Printf("Error uploading document %s: %v\n", file, err)
-									continue
-								}
-								documentCount++
-								fmt.Printf("\rDocuments loaded: %d", documentCount)
-							}
-						} else if stat.Mode().IsRegular() {
-							err := uploadDocument(resolvedPath, BASE_URL, tag, filepath.Base(resolvedPath))
-							if err != nil {
-								fmt.Printf("Error uploading document %s: %v\n", resolvedPath, err)
-								continue
-							}
-							documentCount++
-							fmt.Printf("\rDocuments loaded: %d", documentCount)
-						}
-					}
+					entries[i].Collection, entries[i].Document = docs[i].Collection, docs[i].Name
+					state.record(entries[i])
+					report.Documents++
+				}
+				cleanup()
+			}
+			state.markRemovedSources(tag, filePath, c.Spec.Sources)
+			if removed := state.removed(tag); len(removed) > 0 && apply.PruneDocuments {
+				logf("\n")
+				pruneDocuments(state, removed, &report)
+			} else if len(removed) > 0 {
+				logf("\n%d documents of %s were uploaded from files that no longer exist (remove them with --prune-documents):\n", len(removed), tag)
+				for _, entry := range removed {
+					logf("  %s\n", entry.displayName())
 				}
 			}
+			if err := state.save(); err != nil {
+				logf("Error saving state file %s: %v\n", state.path, err)
+			}
+			report.applied(m.Kind, m.Metadata.Name, action)
 		case Model:
+			if apply.PullBaseModels {
+				if err := ensureBaseModel(c); err != nil {
+					logf("Error pulling base model for %s: %v\n", filePath, err)
+					report.failed(m.Kind, m.Metadata.Name, err)
+					continue
+				}
+			}
+			action := resourceAction(m)
 			err := processModel(c)
 			if err != nil {
-				fmt.Printf("Error processing model %s: %v\n", filePath, err)
+				logf("Error processing model %s: %v\n", filePath, err)
+				report.failed(m.Kind, m.Metadata.Name, err)
 				continue
 			}
 			modelCount++
+			report.applied(m.Kind, m.Metadata.Name, action)
 		default:
-			fmt.Printf("Skipped due to unknown kind in file %s\n", filePath)
+			action := resourceAction(m)
+			if err := applyResource(m); err != nil {
+				logf("Error processing %s %s: %v\n", m.Kind, filePath, err)
+				report.failed(m.Kind, m.Metadata.Name, err)
+				continue
+			}
+			logf("%s %s applied\n", m.Kind, m.Metadata.Name)
+			report.applied(m.Kind, m.Metadata.Name, action)
 		}
 	}
+	notifyWebhooks(webhooks, report)
 
-	if documentCount > 0 {
-		fmt.Printf("\nAll Documents loaded successfully.\n")
+	if progress.loaded > 0 {
+		logf("\nAll Documents loaded successfully.\n")
+	}
+	if report.Unchanged > 0 {
+		logf("\n%d unchanged documents not uploaded again.\n", report.Unchanged)
+	}
+	if len(report.Skipped) > 0 {
+		logf("\n%d documents skipped:\n", len(report.Skipped))
+		for _, file := range report.Skipped {
+			logf("  %s: %s\n", file.Name, file.Reason)
+		}
 	}
 	if modelCount > 0 {
-		fmt.Printf("\nAll Models loaded successfully.\n")
+		logf("\nAll Models loaded successfully.\n")
 	}
 	return nil
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		os.Exit(1)
-	}
-
-	filePath := os.Args[1]
+func collectPaths(filePath string, opts *manifestOptions) ([]string, error) {
 	resolvedPath, _ := filepath.Abs(filePath)
 
-	var paths []string
+	if opts.Overlay != "" {
+		baseDir, err := loadOverlay(resolvedPath, opts.Overlay, opts)
+		if err != nil {
+			return nil, err
+		}
+		resolvedPath = baseDir
+	}
 
 	if stat, err := os.Stat(resolvedPath); err == nil && stat.IsDir() {
 		files, err := processDirectory(resolvedPath)
 		if err != nil {
-			fmt.Printf("Error processing directory: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("error processing directory: %v", err)
 		}
-		paths = files
-	} else {
-		paths = append(paths, resolvedPath)
+		return files, nil
+	}
+	return []string{resolvedPath}, nil
+}
+
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func runApply(args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	file := fs.String("f", "", "path to a definition file or directory")
+	fromGit := fs.String("from-git", "", "git repository to read the definitions from")
+	gitPath := fs.String("path", "", "path of the definitions inside the --from-git repository")
+	gitRef := fs.String("ref", "", "branch, tag or commit of the --from-git repository")
+	pull := fs.Bool("pull", false, "pull missing Ollama base models before creating models")
+	force := fs.Bool("force-upload", false, "upload documents even when their content is already on the server")
+	onConflict := fs.String("on-conflict", "update", "what to do when a document with the same name exists: update, skip or duplicate")
+	prune := fs.Bool("prune-documents", false, "delete documents uploaded from files that were removed from their source")
+	statePath := fs.String("state", "", "state file recording the uploaded documents (default ~/.oictl/state/<server>.json)")
+	parallel := fs.Int("parallel", 4, "number of documents uploaded concurrently")
+	fs.IntVar(parallel, "j", 4, "shorthand for --parallel")
+	compress := fs.Bool("compress", false, "gzip text documents when uploading them")
+	bwlimit := fs.String("bwlimit", "", "maximum upload bandwidth across all workers, e.g. 5MB/s (default unlimited)")
+	maxRate := fs.Float64("max-rate", 0, "maximum number of requests per second sent to the server (default unlimited)")
+	mf := addManifestFlags(fs)
+	fs.Parse(args)
+
+	opts, err := mf.options()
+	if err != nil {
+		return err
+	}
+	opts.ResolveSecrets = true
+	requestLimiter.setMaxRate(*maxRate)
+	if *onConflict != "update" && *onConflict != "skip" && *onConflict != "duplicate" {
+		return fmt.Errorf("invalid --on-conflict %q, expected update, skip or duplicate", *onConflict)
+	}
+	compressUploads.Store(*compress)
+	reuseUploads.Store(!*force)
+	if err := uploadBandwidth.setLimit(*bwlimit); err != nil {
+		return err
+	}
+
+	filePath := *file
+	if filePath == "" && fs.NArg() > 0 {
+		filePath = fs.Arg(0)
+	}
+
+	if *fromGit != "" {
+		tempDir, err := os.MkdirTemp("", "oictl_manifests_")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tempDir)
+
+		repoDir := filepath.Join(tempDir, "repo")
+		if err := cloneGitRepo(*fromGit, repoDir); err != nil {
+			return err
+		}
+		if err := checkoutRepoRef(repoDir, *gitRef); err != nil {
+			return err
+		}
+		filePath = filepath.Join(repoDir, filepath.Clean("/"+*gitPath))
+	}
+
+	if filePath == "" {
+		return fmt.Errorf("no definition path given")
 	}
 
-	err := handleOictl(paths)
+	paths, err := collectPaths(filePath, &opts)
 	if err != nil {
-		fmt.Printf("An error occurred: %v\n", err)
+		return err
+	}
+	return handleOictl(paths, opts, applyOptions{PullBaseModels: *pull, Parallel: *parallel, ForceUpload: *force, StatePath: *statePath, OnConflict: *onConflict, PruneDocuments: *prune})
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "apply":
+		err = runApply(os.Args[2:])
+	case "backup":
+		err = runBackup(os.Args[2:])
+	case "chat":
+		err = runChat(os.Args[2:])
+	case "delete":
+		err = runDelete(os.Args[2:])
+	case "diff":
+		err = runDiff(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "graph":
+		err = runGraph(os.Args[2:])
+	case "migrate":
+		err = runMigrate(os.Args[2:])
+	case "render":
+		err = runRender(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	default:
+		err = runApply(os.Args[1:])
+	}
+	if err != nil {
+		logf("An error occurred: %v\n", err)
+		os.Exit(1)
 	}
 }
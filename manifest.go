@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type manifest struct {
+	Path     string      `yaml:"-"`
+	Kind     string      `yaml:"kind"`
+	Metadata Metadata    `yaml:"metadata"`
+	Config   interface{} `yaml:"-"`
+}
+
+func (m manifest) key() string {
+	return resourceKey(m.Kind, m.Metadata.Name)
+}
+
+func loadManifests(paths []string, opts manifestOptions) []manifest {
+	var manifests []manifest
+	for _, filePath := range paths {
+		m, err := parseYamlFile(filePath, opts)
+		if err != nil {
+			errorf("Skipped %s: %v\n", filePath, err)
+			continue
+		}
+		if !opts.Selector.matches(m.Metadata.Labels) {
+			debugf("%s does not match selector %s\n", m.key(), opts.Selector)
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests
+}
+
+type selectorRequirement struct {
+	Key      string
+	Operator string
+	Value    string
+}
+
+type labelSelector []selectorRequirement
+
+func parseSelector(selector string) (labelSelector, error) {
+	var requirements labelSelector
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		var req selectorRequirement
+		switch {
+		case strings.Contains(term, "!="):
+			key, value, _ := strings.Cut(term, "!=")
+			req = selectorRequirement{Key: key, Operator: "!=", Value: value}
+		case strings.Contains(term, "=="):
+			key, value, _ := strings.Cut(term, "==")
+			req = selectorRequirement{Key: key, Operator: "=", Value: value}
+		case strings.Contains(term, "="):
+			key, value, _ := strings.Cut(term, "=")
+			req = selectorRequirement{Key: key, Operator: "=", Value: value}
+		case strings.HasPrefix(term, "!"):
+			req = selectorRequirement{Key: strings.TrimPrefix(term, "!"), Operator: "!"}
+		default:
+			req = selectorRequirement{Key: term, Operator: "exists"}
+		}
+
+		req.Key = strings.TrimSpace(req.Key)
+		req.Value = strings.TrimSpace(req.Value)
+		if req.Key == "" {
+			return nil, fmt.Errorf("invalid selector %q", selector)
+		}
+		requirements = append(requirements, req)
+	}
+	return requirements, nil
+}
+
+func (s labelSelector) matches(labels map[string]string) bool {
+	for _, req := range s {
+		value, ok := labels[req.Key]
+		switch req.Operator {
+		case "=":
+			if !ok || value != req.Value {
+				return false
+			}
+		case "!=":
+			if ok && value == req.Value {
+				return false
+			}
+		case "!":
+			if ok {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s labelSelector) String() string {
+	var terms []string
+	for _, req := range s {
+		switch req.Operator {
+		case "exists":
+			terms = append(terms, req.Key)
+		case "!":
+			terms = append(terms, "!"+req.Key)
+		default:
+			terms = append(terms, req.Key+req.Operator+req.Value)
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+var kindOrder = map[string]int{
+	"Config":                1,
+	"AuthProvider":          1,
+	"RAGConfig":             2,
+	"Connection":            3,
+	"WebSearchConfig":       2,
+	"ImageGenerationConfig": 2,
+	"AudioConfig":           2,
+	"User":                  5,
+	"Group":                 7,
+	"Documents":             10,
+	"Knowledge":             10,
+	"Prompt":                20,
+	"Tool":                  30,
+	"Function":              30,
+	"Pipeline":              30,
+	"Model":                 50,
+	"ArenaModel":            60,
+	"Folder":                60,
+	"ChatArchive":           70,
+	"Banner":                60,
+}
+
+func applyResource(m manifest) error {
+	switch c := m.Config.(type) {
+	case Prompt:
+		return processPrompt(c)
+	case Tool:
+		return processTool(c, m.Path)
+	case Function:
+		return processFunction(c, m.Path)
+	case Knowledge:
+		return processKnowledge(c, m.Path)
+	case User:
+		return processUser(c)
+	case Group:
+		return processGroup(c)
+	case InstanceConfig:
+		return processConfig(c)
+	case RAGConfig:
+		return processRAGConfig(c)
+	case WebSearchConfig:
+		return processWebSearchConfig(c)
+	case ImageGenerationConfig:
+		return processImageGenerationConfig(c)
+	case AudioConfig:
+		return processAudioConfig(c)
+	case Banner:
+		return processBanner(c)
+	case Pipeline:
+		return processPipeline(c, m.Path)
+	case ArenaModel:
+		return processArenaModel(c)
+	case Folder:
+		return processFolder(c)
+	case ChatArchive:
+		return processChatArchive(c, m.Path)
+	case Connection:
+		return processConnection(c)
+	case AuthProvider:
+		return processAuthProvider(c)
+	}
+	return fmt.Errorf("unsupported kind %s", m.Kind)
+}
+
+func deleteResource(m manifest) error {
+	switch c := m.Config.(type) {
+	case Prompt:
+		return deletePrompt(c)
+	case Tool:
+		return deleteTool(c)
+	case Function:
+		return deleteFunction(c)
+	case Knowledge:
+		return deleteKnowledge(c)
+	case User:
+		return deleteUser(c)
+	case Group:
+		return deleteGroup(c)
+	case Banner:
+		return deleteBanner(c)
+	case Pipeline:
+		return deletePipeline(c)
+	case ArenaModel:
+		return deleteArenaModel(c)
+	case Folder:
+		return deleteFolder(c)
+	case Connection:
+		return deleteConnection(c)
+	case AuthProvider:
+		return deleteAuthProvider(c)
+	}
+	return fmt.Errorf("deleting %s is not supported", m.Kind)
+}
+
+func resolveDependency(dep string, manifests []manifest) []int {
+	var matches []int
+	for i, m := range manifests {
+		if dep == m.key() || (!strings.Contains(dep, "/") && dep == m.Metadata.Name) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+func sortManifests(manifests []manifest) ([]manifest, error) {
+	dependents := make([][]int, len(manifests))
+	inDegree := make([]int, len(manifests))
+	for i, m := range manifests {
+		for _, dep := range m.Metadata.DependsOn {
+			matches := resolveDependency(dep, manifests)
+			if len(matches) == 0 {
+				logf("Warning: %s depends on %s which is not part of this run\n", m.key(), dep)
+				continue
+			}
+			for _, j := range matches {
+				dependents[j] = append(dependents[j], i)
+				inDegree[i]++
+			}
+		}
+	}
+
+	var sorted []manifest
+	done := make([]bool, len(manifests))
+	for len(sorted) < len(manifests) {
+		next := -1
+		for i, m := range manifests {
+			if done[i] || inDegree[i] != 0 {
+				continue
+			}
+			if next == -1 || kindOrder[m.Kind] < kindOrder[manifests[next].Kind] {
+				next = i
+			}
+		}
+		if next == -1 {
+			var cycle []string
+			for i, m := range manifests {
+				if !done[i] {
+					cycle = append(cycle, m.key())
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between %s", strings.Join(cycle, ", "))
+		}
+
+		done[next] = true
+		sorted = append(sorted, manifests[next])
+		for _, dependent := range dependents[next] {
+			inDegree[dependent]--
+		}
+	}
+	return sorted, nil
+}
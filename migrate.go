@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+type migrationState struct {
+	Documents map[string]bool `json:"documents"`
+	Models    map[string]bool `json:"models"`
+}
+
+func loadMigrationState(path string) (*migrationState, error) {
+	state := &migrationState{Documents: map[string]bool{}, Models: map[string]bool{}}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("failed to parse migration state %s: %v", path, err)
+	}
+	return state, nil
+}
+
+func (s *migrationState) save(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+type serverInventory struct {
+	models    []Model
+	documents []Document
+	files     []ServerFile
+}
+
+func readInventory() (serverInventory, error) {
+	var source serverInventory
+	models, err := getModels()
+	if err != nil {
+		return source, err
+	}
+	groups, users, err := accessLookups()
+	if err != nil {
+		return source, err
+	}
+	for _, model := range models {
+		source.models = append(source.models, modelToManifest(model, groups, users))
+	}
+
+	source.documents, err = getDocs(TOKEN)
+	return source, err
+}
+
+func (s serverInventory) fileFor(doc Document) (ServerFile, bool) {
+	for _, f := range s.files {
+		if f.Filename == doc.Filename || f.Meta.Name == doc.Filename {
+			return f, true
+		}
+	}
+	return ServerFile{}, false
+}
+
+func migrateDocument(from, to Context, source serverInventory, doc Document) error {
+	f, ok := source.fileFor(doc)
+	if !ok {
+		return fmt.Errorf("file contents are not available on the source server")
+	}
+
+	file, err := os.CreateTemp("", "oictl_migrate_")
+	if err != nil {
+		return err
+	}
+	tempFile := file.Name()
+	defer os.Remove(tempFile)
+	err = withContext(from, func() error {
+		return copyFileContent(f.ID, file)
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	var tags []string
+	for _, tag := range doc.Content.Tags {
+		tags = append(tags, tag.Name)
+	}
+	return withContext(to, func() error {
+		return uploadDocument(tempFile, BASE_URL, tags, doc.Filename, Metadata{Labels: doc.Content.Labels, Annotations: doc.Content.Annotations})
+	})
+}
+
+func migrate(from, to Context, statePath string, overwrite bool) error {
+	state, err := loadMigrationState(statePath)
+	if err != nil {
+		return err
+	}
+
+	var source serverInventory
+	err = withContext(from, func() error {
+		source, err = readInventory()
+		if err != nil {
+			return err
+		}
+		if err := apiRequest("GET", fmt.Sprintf("%s/api/v1/files/", BASE_URL), nil, &source.files); err != nil {
+			logf("Warning: cannot list files on the source server, documents cannot be copied: %v\n", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read source %s: %v", from.Name, err)
+	}
+
+	existingDocs := make(map[string]bool)
+	existingModels := make(map[string]bool)
+	err = withContext(to, func() error {
+		docs, err := getDocs(TOKEN)
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			existingDocs[doc.Name] = true
+		}
+		models, err := getModels()
+		if err != nil {
+			return err
+		}
+		for _, model := range models {
+			existingModels[model.ID] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read destination %s: %v", to.Name, err)
+	}
+
+	total := len(source.documents) + len(source.models)
+	done, failed := 0, 0
+	progress := func(kind, name, status string) {
+		done++
+		logf("[%d/%d] %s %s %s\n", done, total, kind, name, status)
+	}
+
+	for _, doc := range source.documents {
+		if state.Documents[doc.Name] {
+			progress("document", doc.Name, "already migrated")
+			continue
+		}
+		if existingDocs[doc.Name] {
+			state.Documents[doc.Name] = true
+			progress("document", doc.Name, "exists on destination, skipped")
+			continue
+		}
+		if err := migrateDocument(from, to, source, doc); err != nil {
+			failed++
+			progress("document", doc.Name, fmt.Sprintf("failed: %v", err))
+			continue
+		}
+		state.Documents[doc.Name] = true
+		if err := state.save(statePath); err != nil {
+			return err
+		}
+		progress("document", doc.Name, "migrated")
+	}
+
+	for _, model := range source.models {
+		name := model.Metadata.Name
+		if state.Models[name] {
+			progress("model", name, "already migrated")
+			continue
+		}
+		if existingModels[name] && !overwrite {
+			state.Models[name] = true
+			progress("model", name, "exists on destination, skipped")
+			continue
+		}
+		err := withContext(to, func() error {
+			if existingModels[name] {
+				return updateModel(model)
+			}
+			return processModel(model)
+		})
+		if err != nil {
+			failed++
+			progress("model", name, fmt.Sprintf("failed: %v", err))
+			continue
+		}
+		state.Models[name] = true
+		if err := state.save(statePath); err != nil {
+			return err
+		}
+		progress("model", name, "migrated")
+	}
+
+	if err := state.save(statePath); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d resources failed to migrate, rerun to resume", failed, total)
+	}
+	logf("Migrated %d resources from %s to %s\n", total, from.Name, to.Name)
+	return nil
+}
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fromName := fs.String("from", "", "source context name or server URL")
+	toName := fs.String("to", "", "destination context name or server URL")
+	statePath := fs.String("state", "", "file recording migrated resources (default .oictl-migrate-<from>-<to>.json)")
+	overwrite := fs.Bool("overwrite", false, "update models that already exist on the destination")
+	fs.Parse(args)
+
+	if *fromName == "" || *toName == "" {
+		return fmt.Errorf("usage: oictl migrate --from <context> --to <context>")
+	}
+	from, err := findContext(*fromName)
+	if err != nil {
+		return err
+	}
+	to, err := findContext(*toName)
+	if err != nil {
+		return err
+	}
+	if *statePath == "" {
+		*statePath = fmt.Sprintf(".oictl-migrate-%s-%s.json", safeArchiveName(from.Name), safeArchiveName(to.Name))
+	}
+
+	return migrate(from, to, *statePath, *overwrite)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func isOciSource(source string) bool {
+	return strings.HasPrefix(source, "oci://")
+}
+
+func handleOciSource(source DocumentSource) ([]string, string, error) {
+	ref := strings.TrimPrefix(source.Source, "oci://")
+	if ref == "" {
+		return nil, "", fmt.Errorf("invalid source %s, expected oci://registry/repository:tag", source.Source)
+	}
+	tempDir, err := os.MkdirTemp("", "oictl_oci_")
+	if err != nil {
+		return nil, "", err
+	}
+
+	output, err := exec.Command("oras", "pull", "--output", tempDir, ref).CombinedOutput()
+	if err != nil {
+		os.RemoveAll(tempDir)
+		if _, lookErr := exec.LookPath("oras"); lookErr != nil {
+			return nil, "", fmt.Errorf("oci sources need the oras CLI: %v", lookErr)
+		}
+		return nil, "", fmt.Errorf("oras pull %s: %v: %s", ref, err, strings.TrimSpace(string(output)))
+	}
+
+	dirs := source.Dir
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	files, err := collectRepoFiles(tempDir, dirs, source)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", err
+	}
+	return files, tempDir, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".tif", ".tiff", ".bmp", ".gif", ".webp"}
+
+type OcrConfig struct {
+	Language  string `yaml:"language,omitempty"`
+	URL       string `yaml:"url,omitempty"`
+	ApiKey    string `yaml:"apiKey,omitempty"`
+	ApiKeyEnv string `yaml:"apiKeyEnv,omitempty"`
+	Model     string `yaml:"model,omitempty"`
+}
+
+func isImageFile(filename string) bool {
+	return containsString(imageExtensions, strings.ToLower(filepath.Ext(filename)))
+}
+
+func tesseractText(imagePath string, config *OcrConfig) (string, error) {
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return "", fmt.Errorf("ocr needs tesseract, or ocr.model for a vision model API, to read %s", filepath.Base(imagePath))
+	}
+	args := []string{imagePath, "stdout"}
+	if config.Language != "" {
+		args = append(args, "-l", config.Language)
+	}
+	cmd := exec.Command("tesseract", args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("tesseract %s: %v: %s", filepath.Base(imagePath), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+func visionModelText(imagePath string, config *OcrConfig) (string, error) {
+	endpoint := fmt.Sprintf("%s/api/chat/completions", BASE_URL)
+	token := TOKEN
+	if config.URL != "" {
+		endpoint = strings.TrimSuffix(config.URL, "/") + "/chat/completions"
+		key, err := resolveApiKey(config.ApiKey, config.ApiKeyEnv)
+		if err != nil {
+			return "", err
+		}
+		token = key
+	}
+	image, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", err
+	}
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(imagePath)))
+	if contentType == "" {
+		contentType = http.DetectContentType(image)
+	}
+	prompt := "Transcribe all text in this image as markdown. Reply with the transcribed text only."
+	if config.Language != "" {
+		prompt += " The text is in " + config.Language + "."
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model":  config.Model,
+		"stream": false,
+		"messages": []map[string]interface{}{{
+			"role": "user",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": prompt},
+				{"type": "image_url", "image_url": map[string]string{"url": fmt.Sprintf("data:%s;base64,%s", contentType, base64.StdEncoding.EncodeToString(image))}},
+			},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to read %s: %s - %s", filepath.Base(imagePath), resp.Status, string(respBody))
+	}
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if len(result.Choices) == 0 {
+		return "", nil
+	}
+	return result.Choices[0].Message.Content, nil
+}
+
+func ocrImageText(imagePath string, config *OcrConfig) (string, error) {
+	var text string
+	var err error
+	if config.Model != "" {
+		text, err = visionModelText(imagePath, config)
+	} else {
+		text, err = tesseractText(imagePath, config)
+	}
+	return strings.TrimSpace(text), err
+}
+
+func ocrPdfText(pdfPath string, config *OcrConfig, tempDir string) (string, error) {
+	if _, err := exec.LookPath("pdftoppm"); err != nil {
+		return "", fmt.Errorf("ocr of scanned PDFs needs pdftoppm (poppler-utils) to render %s", filepath.Base(pdfPath))
+	}
+	pagesDir := filepath.Join(tempDir, "pages")
+	if err := os.MkdirAll(pagesDir, 0755); err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(pagesDir)
+	output, err := exec.Command("pdftoppm", "-r", "300", "-png", pdfPath, filepath.Join(pagesDir, "page")).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("pdftoppm %s: %v: %s", filepath.Base(pdfPath), err, strings.TrimSpace(string(output)))
+	}
+	pages, err := filepath.Glob(filepath.Join(pagesDir, "page-*.png"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(pages)
+
+	var texts []string
+	for _, page := range pages {
+		text, err := ocrImageText(page, config)
+		if err != nil {
+			return "", err
+		}
+		if text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+func ocrImageFile(file sourceFile, config *OcrConfig, tempDir string) ([]sourceFile, error) {
+	text, err := ocrImageText(file.Path, config)
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		logf("No text found in %s, uploading the image unchanged\n", file.Filename)
+		return []sourceFile{file}, nil
+	}
+	return writeTextDocument(file, text, tempDir)
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	officeExtensions = []string{".docx", ".pptx", ".odt"}
+	pptxSlidePattern = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+)
+
+func isOfficeFile(filename string) bool {
+	return containsString(officeExtensions, strings.ToLower(filepath.Ext(filename)))
+}
+
+type officeText struct {
+	doc    strings.Builder
+	para   strings.Builder
+	prefix string
+	cell   []string
+	row    []string
+	rows   int
+	inCell bool
+}
+
+func (o *officeText) endParagraph() {
+	text := strings.TrimSpace(spacesPattern.ReplaceAllString(o.para.String(), " "))
+	prefix := o.prefix
+	o.para.Reset()
+	o.prefix = ""
+	if text == "" {
+		return
+	}
+	if o.inCell {
+		o.cell = append(o.cell, text)
+		return
+	}
+	o.doc.WriteString(prefix + text + "\n\n")
+}
+
+func (o *officeText) startCell() {
+	o.inCell = true
+	o.cell = nil
+}
+
+func (o *officeText) endCell() {
+	o.endParagraph()
+	o.row = append(o.row, markdownCell(strings.Join(o.cell, "\n")))
+	o.inCell = false
+}
+
+func (o *officeText) endRow() {
+	if len(o.row) == 0 {
+		return
+	}
+	fmt.Fprintf(&o.doc, "| %s |\n", strings.Join(o.row, " | "))
+	if o.rows == 0 {
+		fmt.Fprintf(&o.doc, "|%s\n", strings.Repeat(" --- |", len(o.row)))
+	}
+	o.rows++
+	o.row = nil
+}
+
+func (o *officeText) endTable() {
+	o.rows = 0
+	o.doc.WriteString("\n")
+}
+
+func headingPrefix(level int) string {
+	if level < 1 {
+		level = 1
+	}
+	if level > 6 {
+		level = 6
+	}
+	return strings.Repeat("#", level) + " "
+}
+
+func ooxmlText(content []byte, o *officeText) error {
+	inText := false
+	decoder := xml.NewDecoder(strings.NewReader(string(content)))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "pStyle":
+				style := strings.ToLower(odsAttr(t, "val"))
+				if style == "title" {
+					o.prefix = headingPrefix(1)
+				} else if level, err := strconv.Atoi(strings.TrimPrefix(style, "heading")); err == nil && strings.HasPrefix(style, "heading") {
+					o.prefix = headingPrefix(level)
+				}
+			case "numPr":
+				if o.prefix == "" {
+					o.prefix = "- "
+				}
+			case "t":
+				inText = true
+			case "tab":
+				o.para.WriteString(" ")
+			case "br", "cr":
+				o.para.WriteString("\n")
+			case "tc":
+				o.startCell()
+			}
+		case xml.CharData:
+			if inText {
+				o.para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				o.endParagraph()
+			case "tc":
+				o.endCell()
+			case "tr":
+				o.endRow()
+			case "tbl":
+				o.endTable()
+			}
+		}
+	}
+}
+
+func odtText(content []byte, o *officeText) error {
+	inParagraph, listItem := 0, false
+	decoder := xml.NewDecoder(strings.NewReader(string(content)))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "h":
+				level, _ := strconv.Atoi(odsAttr(t, "outline-level"))
+				o.prefix = headingPrefix(level)
+				inParagraph++
+			case "p":
+				if listItem {
+					o.prefix = "- "
+					listItem = false
+				}
+				inParagraph++
+			case "list-item":
+				listItem = true
+			case "s":
+				count, _ := strconv.Atoi(odsAttr(t, "c"))
+				if count < 1 {
+					count = 1
+				}
+				o.para.WriteString(strings.Repeat(" ", count))
+			case "tab":
+				o.para.WriteString(" ")
+			case "line-break":
+				o.para.WriteString("\n")
+			case "table-cell":
+				o.startCell()
+			}
+		case xml.CharData:
+			if inParagraph > 0 {
+				o.para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "h", "p":
+				inParagraph--
+				if inParagraph == 0 {
+					o.endParagraph()
+				}
+			case "table-cell":
+				o.endCell()
+			case "table-row":
+				o.endRow()
+			case "table":
+				o.endTable()
+			}
+		}
+	}
+}
+
+func readOfficeText(filePath string) (string, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	var o officeText
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".docx":
+		content, err := readZipFile(archive, "word/document.xml")
+		if err != nil {
+			return "", fmt.Errorf("not a docx document: %v", err)
+		}
+		err = ooxmlText(content, &o)
+		if err != nil {
+			return "", err
+		}
+	case ".pptx":
+		slides := make(map[int]string)
+		var numbers []int
+		for _, f := range archive.File {
+			if match := pptxSlidePattern.FindStringSubmatch(f.Name); match != nil {
+				n, _ := strconv.Atoi(match[1])
+				slides[n] = f.Name
+				numbers = append(numbers, n)
+			}
+		}
+		sort.Ints(numbers)
+		for _, n := range numbers {
+			content, err := readZipFile(archive, slides[n])
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&o.doc, "## Slide %d\n\n", n)
+			if err := ooxmlText(content, &o); err != nil {
+				return "", fmt.Errorf("slide %d: %v", n, err)
+			}
+		}
+	case ".odt":
+		content, err := readZipFile(archive, "content.xml")
+		if err != nil {
+			return "", fmt.Errorf("not an odt document: %v", err)
+		}
+		if err := odtText(content, &o); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(o.doc.String()), nil
+}
+
+func pandocText(filePath string) (string, error) {
+	cmd := exec.Command("pandoc", "--to", "gfm", "--wrap", "none", filePath)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pandoc %s: %v: %s", filepath.Base(filePath), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func convertOfficeFile(file sourceFile, tempDir string) ([]sourceFile, error) {
+	var text string
+	var err error
+	if _, lookErr := exec.LookPath("pandoc"); lookErr == nil && !strings.EqualFold(filepath.Ext(file.Filename), ".pptx") {
+		text, err = pandocText(file.Path)
+	} else {
+		text, err = readOfficeText(file.Path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", file.Filename, err)
+	}
+	if text == "" {
+		logf("No text found in %s, uploading it unchanged\n", file.Filename)
+		return []sourceFile{file}, nil
+	}
+	return writeTextDocument(file, text, tempDir)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+func baseModelAvailable(id string) (bool, error) {
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := apiRequest("GET", fmt.Sprintf("%s/api/models", BASE_URL), nil, &models); err != nil {
+		return false, err
+	}
+	for _, model := range models.Data {
+		if model.ID == id || (!strings.Contains(id, ":") && model.ID == id+":latest") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func pullOllamaModel(name string) error {
+	body, _ := json.Marshal(map[string]interface{}{"name": name, "stream": true})
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/ollama/api/pull", BASE_URL), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", TOKEN))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to pull %s: %s - %s", name, resp.Status, string(respBody))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	status := ""
+	for scanner.Scan() {
+		var progress struct {
+			Status    string `json:"status"`
+			Error     string `json:"error"`
+			Total     int64  `json:"total"`
+			Completed int64  `json:"completed"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &progress); err != nil {
+			continue
+		}
+		if progress.Error != "" {
+			logf("\n")
+			return fmt.Errorf("failed to pull %s: %s", name, progress.Error)
+		}
+		status = progress.Status
+		if progress.Total > 0 {
+			logf("\rPulling %s: %s %d%%   ", name, progress.Status, progress.Completed*100/progress.Total)
+		} else {
+			logf("\rPulling %s: %s   ", name, progress.Status)
+		}
+	}
+	logf("\n")
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if status != "success" {
+		return fmt.Errorf("pull of %s ended without success", name)
+	}
+	return nil
+}
+
+func ensureBaseModel(config Model) error {
+	id := config.Spec.BaseModelID
+	if id == "" {
+		return nil
+	}
+	available, err := baseModelAvailable(id)
+	if err != nil || available {
+		return err
+	}
+	logf("Base model %s of %s is not available, pulling it through Ollama\n", id, config.Metadata.Name)
+	return pullOllamaModel(id)
+}
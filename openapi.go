@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	openapiMethods      = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+	operationNameFilter = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+func resolveOpenapiRefs(spec map[string]interface{}, value interface{}, resolving map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, "#/") {
+			if resolving[ref] {
+				return map[string]interface{}{"$ref": ref + " (recursive)"}
+			}
+			var target interface{} = spec
+			for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+				key = strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~")
+				m, ok := target.(map[string]interface{})
+				if !ok {
+					return v
+				}
+				target = m[key]
+			}
+			resolving[ref] = true
+			resolved := resolveOpenapiRefs(spec, target, resolving)
+			delete(resolving, ref)
+			return resolved
+		}
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = resolveOpenapiRefs(spec, item, resolving)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = resolveOpenapiRefs(spec, item, resolving)
+		}
+		return out
+	}
+	return value
+}
+
+func yamlBlock(value interface{}) string {
+	content, err := marshalManifest(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return "```yaml\n" + string(content) + "```\n"
+}
+
+func operationDocument(title, method, route string, pathItem, operation map[string]interface{}) string {
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "# %s %s\n\n", strings.ToUpper(method), route)
+	fmt.Fprintf(&doc, "API: %s\n", title)
+	for _, key := range []string{"operationId", "summary"} {
+		if value, ok := operation[key].(string); ok {
+			fmt.Fprintf(&doc, "%s: %s\n", key, value)
+		}
+	}
+	if tags, ok := operation["tags"].([]interface{}); ok && len(tags) > 0 {
+		fmt.Fprintf(&doc, "tags: %s\n", strings.Trim(fmt.Sprint(tags), "[]"))
+	}
+	if description, ok := operation["description"].(string); ok {
+		fmt.Fprintf(&doc, "\n%s\n", strings.TrimSpace(description))
+	}
+
+	var parameters []interface{}
+	if shared, ok := pathItem["parameters"].([]interface{}); ok {
+		parameters = append(parameters, shared...)
+	}
+	if own, ok := operation["parameters"].([]interface{}); ok {
+		parameters = append(parameters, own...)
+	}
+	if len(parameters) > 0 {
+		doc.WriteString("\n## Parameters\n\n")
+		doc.WriteString(yamlBlock(parameters))
+	}
+	if body, ok := operation["requestBody"]; ok {
+		doc.WriteString("\n## Request body\n\n")
+		doc.WriteString(yamlBlock(body))
+	}
+	if responses, ok := operation["responses"].(map[string]interface{}); ok {
+		doc.WriteString("\n## Responses\n")
+		var codes []string
+		for code := range responses {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&doc, "\n### %s\n\n", code)
+			doc.WriteString(yamlBlock(responses[code]))
+		}
+	}
+	return doc.String()
+}
+
+func splitOpenapiSpec(file sourceFile, tempDir string) ([]sourceFile, error) {
+	content, err := os.ReadFile(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	var spec map[string]interface{}
+	if err := yaml.Unmarshal(content, &spec); err != nil || (spec["openapi"] == nil && spec["swagger"] == nil) {
+		return []sourceFile{file}, nil
+	}
+
+	title := file.Filename
+	if info, ok := spec["info"].(map[string]interface{}); ok {
+		if t, ok := info["title"].(string); ok {
+			title = t
+		}
+	}
+	paths, _ := spec["paths"].(map[string]interface{})
+	var routes []string
+	for route := range paths {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	base := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	var files []sourceFile
+	for _, route := range routes {
+		pathItem, _ := resolveOpenapiRefs(spec, paths[route], map[string]bool{}).(map[string]interface{})
+		for _, method := range openapiMethods {
+			operation, ok := pathItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := operation["operationId"].(string)
+			if id == "" {
+				id = method + "_" + strings.Trim(route, "/")
+			}
+			name := fmt.Sprintf("%s-%s.md", base, strings.Trim(operationNameFilter.ReplaceAllString(id, "_"), "_"))
+			target := filepath.Join(tempDir, fmt.Sprintf("%d-%s", len(files), safeArchiveName(name)))
+			if err := os.WriteFile(target, []byte(operationDocument(title, method, route, pathItem, operation)), 0644); err != nil {
+				return nil, err
+			}
+			files = append(files, sourceFile{Path: target, Filename: name, Tags: file.Tags})
+		}
+	}
+	if len(files) == 0 {
+		return []sourceFile{file}, nil
+	}
+	return files, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+var overlayMergeKeys = map[string]string{
+	"spec.sources":        "source",
+	"spec.meta.knowledge": "tags",
+}
+
+func resourceKey(kind, name string) string {
+	return fmt.Sprintf("%s/%s", kind, name)
+}
+
+func loadOverlay(root, overlay string, opts *manifestOptions) (string, error) {
+	overlayDir := filepath.Join(root, "overlays", overlay)
+	if stat, err := os.Stat(overlay); err == nil && stat.IsDir() {
+		overlayDir = overlay
+	}
+	if stat, err := os.Stat(overlayDir); err != nil || !stat.IsDir() {
+		return "", fmt.Errorf("overlay %s not found at %s", overlay, overlayDir)
+	}
+
+	baseDir := root
+	if stat, err := os.Stat(filepath.Join(root, "base")); err == nil && stat.IsDir() {
+		baseDir = filepath.Join(root, "base")
+	}
+
+	files, err := processDirectory(overlayDir)
+	if err != nil {
+		return "", err
+	}
+
+	opts.Patches = make(map[string][]map[string]interface{})
+	patchFiles := make(map[string]string)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		content, err = renderManifest(file, content, *opts)
+		if err != nil {
+			return "", err
+		}
+
+		var patch map[string]interface{}
+		if err := yaml.Unmarshal(content, &patch); err != nil {
+			return "", fmt.Errorf("failed to parse overlay patch %s: %v", file, err)
+		}
+		kind, _ := patch["kind"].(string)
+		metadata, _ := patch["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		if kind == "" || name == "" {
+			return "", fmt.Errorf("overlay patch %s must set kind and metadata.name", file)
+		}
+		key := resourceKey(kind, name)
+		opts.Patches[key] = append(opts.Patches[key], patch)
+		patchFiles[key] = file
+	}
+
+	baseFiles, err := processDirectory(baseDir)
+	if err != nil {
+		return "", err
+	}
+	baseKeys := make(map[string]bool)
+	for _, file := range baseFiles {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if content, err = renderManifest(file, content, *opts); err != nil {
+			continue
+		}
+		var base struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		if yaml.Unmarshal(content, &base) == nil {
+			baseKeys[resourceKey(base.Kind, base.Metadata.Name)] = true
+		}
+	}
+	for key, file := range patchFiles {
+		if !baseKeys[key] {
+			return "", fmt.Errorf("overlay patch %s patches %s, which is not defined in %s", file, key, baseDir)
+		}
+	}
+
+	return baseDir, nil
+}
+
+func applyPatches(content []byte, opts manifestOptions) ([]byte, error) {
+	if len(opts.Patches) == 0 {
+		return content, nil
+	}
+
+	var base map[string]interface{}
+	if err := yaml.Unmarshal(content, &base); err != nil {
+		return nil, err
+	}
+	kind, _ := base["kind"].(string)
+	metadata, _ := base["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+
+	patches := opts.Patches[resourceKey(kind, name)]
+	if len(patches) == 0 {
+		return content, nil
+	}
+	for _, patch := range patches {
+		strategicMerge(base, patch, "")
+		if kind == "Documents" {
+			removeSources(base, patch)
+		}
+	}
+	return yaml.Marshal(base)
+}
+
+func strategicMerge(dst, src map[string]interface{}, path string) {
+	for key, value := range src {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		if keyPath == "spec.removeSources" {
+			continue
+		}
+		if value == nil {
+			delete(dst, key)
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if existing, ok := dst[key].(map[string]interface{}); ok {
+				strategicMerge(existing, v, keyPath)
+				continue
+			}
+		case []interface{}:
+			if mergeKey, ok := overlayMergeKeys[keyPath]; ok {
+				existing, _ := dst[key].([]interface{})
+				dst[key] = mergeList(existing, v, mergeKey, keyPath)
+				continue
+			}
+		}
+		dst[key] = value
+	}
+}
+
+func mergeList(dst, src []interface{}, mergeKey, path string) []interface{} {
+	for _, item := range src {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			dst = append(dst, item)
+			continue
+		}
+		merged := false
+		for _, existing := range dst {
+			existingMap, ok := existing.(map[string]interface{})
+			if ok && existingMap[mergeKey] != nil && existingMap[mergeKey] == itemMap[mergeKey] {
+				strategicMerge(existingMap, itemMap, path)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			dst = append(dst, item)
+		}
+	}
+	return dst
+}
+
+func removeSources(base, patch map[string]interface{}) {
+	patchSpec, _ := patch["spec"].(map[string]interface{})
+	removals, _ := patchSpec["removeSources"].([]interface{})
+	if len(removals) == 0 {
+		return
+	}
+
+	spec, _ := base["spec"].(map[string]interface{})
+	sources, _ := spec["sources"].([]interface{})
+	var kept []interface{}
+	for _, source := range sources {
+		sourceMap, _ := source.(map[string]interface{})
+		removed := false
+		for _, removal := range removals {
+			if sourceMap["source"] == removal {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			kept = append(kept, source)
+		}
+	}
+	spec["sources"] = kept
+}
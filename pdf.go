@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func pdfText(filePath string) (string, error) {
+	if _, err := exec.LookPath("pdftotext"); err != nil {
+		return "", fmt.Errorf("extractText needs pdftotext (poppler-utils) to convert %s", filepath.Base(filePath))
+	}
+	cmd := exec.Command("pdftotext", "-layout", "-enc", "UTF-8", filePath, "-")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pdftotext %s: %v: %s", filepath.Base(filePath), err, strings.TrimSpace(stderr.String()))
+	}
+	text := strings.ReplaceAll(string(output), "\f", "\n\n")
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(text, "\n\n")), nil
+}
+
+func extractPdfText(file sourceFile, source DocumentSource, tempDir string) ([]sourceFile, error) {
+	text, err := pdfText(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	if text == "" && source.Ocr != nil {
+		if text, err = ocrPdfText(file.Path, source.Ocr, tempDir); err != nil {
+			return nil, err
+		}
+	} else if !source.ExtractText {
+		return []sourceFile{file}, nil
+	}
+	if text == "" {
+		logf("No text found in %s, uploading the PDF unchanged\n", file.Filename)
+		return []sourceFile{file}, nil
+	}
+	return writeTextDocument(file, text, tempDir)
+}
+
+func writeTextDocument(file sourceFile, text, tempDir string) ([]sourceFile, error) {
+	base := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	target := filepath.Join(tempDir, safeArchiveName(base+".md"))
+	if err := os.WriteFile(target, []byte("# "+base+"\n\n"+text+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return []sourceFile{{Path: target, Filename: base + ".md", Tags: file.Tags}}, nil
+}
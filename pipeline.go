@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type Pipeline struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Server  string                 `yaml:"server,omitempty"`
+		File    string                 `yaml:"file,omitempty"`
+		Content string                 `yaml:"content,omitempty"`
+		URL     string                 `yaml:"url,omitempty"`
+		Valves  map[string]interface{} `yaml:"valves,omitempty"`
+	} `yaml:"spec"`
+}
+
+func pipelineServerIndex(server string) (int, error) {
+	var servers struct {
+		Data []struct {
+			Url string `json:"url"`
+			Idx int    `json:"idx"`
+		} `json:"data"`
+	}
+	if err := apiRequest("GET", fmt.Sprintf("%s/api/v1/pipelines/list", BASE_URL), nil, &servers); err != nil {
+		return 0, err
+	}
+	if len(servers.Data) == 0 {
+		return 0, fmt.Errorf("no Pipelines server is connected")
+	}
+	if server == "" {
+		return servers.Data[0].Idx, nil
+	}
+	for _, s := range servers.Data {
+		if strings.TrimSuffix(s.Url, "/") == strings.TrimSuffix(server, "/") {
+			return s.Idx, nil
+		}
+	}
+	return 0, fmt.Errorf("pipelines server %s is not connected", server)
+}
+
+func uploadPipeline(name, content string, urlIdx int) error {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", name+".py")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(part, content); err != nil {
+		return err
+	}
+	writer.WriteField("urlIdx", strconv.Itoa(urlIdx))
+	writer.Close()
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/pipelines/upload", BASE_URL), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", TOKEN))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to upload pipeline %s: %s - %s", name, resp.Status, string(respBody))
+	}
+	return nil
+}
+
+func pipelineUrl(id, action string, urlIdx int) string {
+	return fmt.Sprintf("%s/api/v1/pipelines/%s%s?urlIdx=%d", BASE_URL, url.PathEscape(id), action, urlIdx)
+}
+
+func processPipeline(config Pipeline, manifestPath string) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	urlIdx, err := pipelineServerIndex(config.Spec.Server)
+	if err != nil {
+		return err
+	}
+
+	id := config.Metadata.Name
+	switch {
+	case config.Spec.URL != "":
+		payload := map[string]interface{}{"url": config.Spec.URL, "urlIdx": urlIdx}
+		var added struct {
+			ID string `json:"id"`
+		}
+		if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/pipelines/add", BASE_URL), payload, &added); err != nil {
+			return err
+		}
+		if added.ID != "" {
+			id = added.ID
+		}
+	default:
+		content := config.Spec.Content
+		if config.Spec.File != "" {
+			if content, err = readSpecFile(manifestPath, config.Spec.File); err != nil {
+				return err
+			}
+		}
+		if content == "" {
+			return fmt.Errorf("pipeline %s needs spec.file, spec.content or spec.url", config.Metadata.Name)
+		}
+		if err := uploadPipeline(id, content, urlIdx); err != nil {
+			return err
+		}
+	}
+
+	if len(config.Spec.Valves) == 0 {
+		return nil
+	}
+	valves := make(map[string]interface{})
+	if err := apiRequest("GET", pipelineUrl(id, "/valves", urlIdx), nil, &valves); err != nil {
+		return err
+	}
+	mergeValues(valves, config.Spec.Valves)
+	return apiRequest("POST", pipelineUrl(id, "/valves/update", urlIdx), valves, nil)
+}
+
+func deletePipeline(config Pipeline) error {
+	urlIdx, err := pipelineServerIndex(config.Spec.Server)
+	if err != nil {
+		return err
+	}
+	payload := map[string]interface{}{"id": config.Metadata.Name, "urlIdx": urlIdx}
+	return apiRequest("DELETE", fmt.Sprintf("%s/api/v1/pipelines/delete", BASE_URL), payload, nil)
+}
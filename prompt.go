@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Prompt struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Command string `yaml:"command,omitempty"`
+		Title   string `yaml:"title"`
+		Content string `yaml:"content"`
+	} `yaml:"spec"`
+}
+
+func (p Prompt) command() string {
+	command := p.Spec.Command
+	if command == "" {
+		command = p.Metadata.Name
+	}
+	return "/" + strings.TrimPrefix(command, "/")
+}
+
+func promptExists(command string) bool {
+	err := apiRequest("GET", fmt.Sprintf("%s/api/v1/prompts/command/%s", BASE_URL, strings.TrimPrefix(command, "/")), nil, nil)
+	return err == nil
+}
+
+func processPrompt(config Prompt) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	command := config.command()
+	payload := map[string]interface{}{
+		"command": command,
+		"title":   config.Spec.Title,
+		"content": config.Spec.Content,
+	}
+
+	if promptExists(command) {
+		return apiRequest("POST", fmt.Sprintf("%s/api/v1/prompts/command/%s/update", BASE_URL, strings.TrimPrefix(command, "/")), payload, nil)
+	}
+	return apiRequest("POST", fmt.Sprintf("%s/api/v1/prompts/create", BASE_URL), payload, nil)
+}
+
+func deletePrompt(config Prompt) error {
+	return apiRequest("DELETE", fmt.Sprintf("%s/api/v1/prompts/command/%s/delete", BASE_URL, strings.TrimPrefix(config.command(), "/")), nil, nil)
+}
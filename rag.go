@@ -0,0 +1,69 @@
+package main
+
+import "fmt"
+
+type RAGConfig struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		ChunkSize          *int     `yaml:"chunkSize,omitempty"`
+		ChunkOverlap       *int     `yaml:"chunkOverlap,omitempty"`
+		TextSplitter       string   `yaml:"textSplitter,omitempty"`
+		EmbeddingEngine    string   `yaml:"embeddingEngine,omitempty"`
+		EmbeddingModel     string   `yaml:"embeddingModel,omitempty"`
+		EmbeddingBatchSize *int     `yaml:"embeddingBatchSize,omitempty"`
+		TopK               *int     `yaml:"topK,omitempty"`
+		RelevanceThreshold *float64 `yaml:"relevanceThreshold,omitempty"`
+		HybridSearch       *bool    `yaml:"hybridSearch,omitempty"`
+		Template           string   `yaml:"template,omitempty"`
+	} `yaml:"spec"`
+}
+
+func processRAGConfig(config RAGConfig) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	if config.Spec.ChunkSize != nil && config.Spec.ChunkOverlap != nil && *config.Spec.ChunkOverlap >= *config.Spec.ChunkSize {
+		return fmt.Errorf("chunk overlap %d must be smaller than chunk size %d", *config.Spec.ChunkOverlap, *config.Spec.ChunkSize)
+	}
+
+	var engine *string
+	switch config.Spec.EmbeddingEngine {
+	case "":
+	case "ollama", "openai":
+		engine = &config.Spec.EmbeddingEngine
+	case "default", "sentence-transformers":
+		engine = new(string)
+	default:
+		return fmt.Errorf("unknown embedding engine %s", config.Spec.EmbeddingEngine)
+	}
+
+	embedding := map[string]interface{}{
+		"embedding_engine":     engine,
+		"embedding_model":      config.Spec.EmbeddingModel,
+		"embedding_batch_size": config.Spec.EmbeddingBatchSize,
+	}
+	if err := updateServerConfig("/api/v1/retrieval/embedding", "/api/v1/retrieval/embedding/update", embedding); err != nil {
+		return err
+	}
+
+	chunk := map[string]interface{}{
+		"chunk": map[string]interface{}{
+			"chunk_size":    config.Spec.ChunkSize,
+			"chunk_overlap": config.Spec.ChunkOverlap,
+			"text_splitter": config.Spec.TextSplitter,
+		},
+	}
+	if err := updateServerConfig("/api/v1/retrieval/config", "/api/v1/retrieval/config/update", chunk); err != nil {
+		return err
+	}
+
+	query := map[string]interface{}{
+		"k":        config.Spec.TopK,
+		"r":        config.Spec.RelevanceThreshold,
+		"hybrid":   config.Spec.HybridSearch,
+		"template": config.Spec.Template,
+	}
+	return updateServerConfig("/api/v1/retrieval/query/settings", "/api/v1/retrieval/query/settings/update", query)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const redactedValue = "[REDACTED]"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+
+	secretPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+		regexp.MustCompile(`(?i)(\b(?:[a-z0-9]+[_-])*(?:api[_-]?key|token|secret|password)\b"?\s*[:=]\s*"?)[^"\s,&}]+`),
+		regexp.MustCompile(`\bsk-[A-Za-z0-9\-_]{8,}`),
+	}
+
+	DEBUG = os.Getenv("OICTL_DEBUG") != ""
+
+	httpClient = &http.Client{Transport: retryTransport{debugTransport{http.DefaultTransport}}}
+)
+
+func init() {
+	registerSecret(TOKEN)
+}
+
+func registerSecret(secret string) {
+	if len(secret) < 4 {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+func redact(s string) string {
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	secretsMu.RUnlock()
+
+	for _, re := range secretPatterns {
+		if re.NumSubexp() > 0 {
+			s = re.ReplaceAllString(s, "${1}"+redactedValue)
+		} else {
+			s = re.ReplaceAllString(s, redactedValue)
+		}
+	}
+	return s
+}
+
+func logf(format string, a ...interface{}) {
+	fmt.Fprint(os.Stdout, redact(fmt.Sprintf(format, a...)))
+}
+
+func errorf(format string, a ...interface{}) {
+	fmt.Fprint(os.Stderr, redact(fmt.Sprintf(format, a...)))
+}
+
+func debugf(format string, a ...interface{}) {
+	if !DEBUG {
+		return
+	}
+	fmt.Fprint(os.Stderr, redact(fmt.Sprintf("[debug] "+format, a...)))
+}
+
+type debugTransport struct {
+	next http.RoundTripper
+}
+
+func (t debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if DEBUG {
+		dump, _ := httputil.DumpRequestOut(req, false)
+		debugf("%s\n", dump)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if DEBUG && err == nil {
+		dump, _ := httputil.DumpResponse(resp, false)
+		debugf("%s\n", dump)
+	}
+	return resp, err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+func renderSources(c Documents, manifestPath string, resolveRemote bool) {
+	logf("# Resolved files for %s:\n", resourceKey(c.Kind, c.Metadata.Name))
+	for _, source := range c.Spec.Sources {
+		logf("#   %s\n", source.Source)
+		if (source.Type != "" || !isLocalSource(source.Source)) && !resolveRemote {
+			logf("#     (remote source, resolved at apply time; use --resolve-remote)\n")
+			continue
+		}
+		source.Incremental = false
+		source.Transcription = nil
+
+		files, skipped, cleanup, err := resolveSource(source, manifestPath)
+		if err != nil {
+			cleanup()
+			logf("#     error: %v\n", err)
+			continue
+		}
+		if len(files) == 0 && len(skipped) == 0 {
+			logf("#     (no files)\n")
+		}
+		for _, file := range files {
+			logf("#     %s\n", file.Path)
+		}
+		for _, file := range skipped {
+			if file.Err != nil {
+				logf("#     %s (error: %v)\n", file.Name, file.Err)
+				continue
+			}
+			logf("#     %s (skipped: %s)\n", file.Name, file.Reason)
+		}
+		cleanup()
+	}
+}
+
+func runRender(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	file := fs.String("f", "", "path to a definition file or directory")
+	resolveRemote := fs.Bool("resolve-remote", false, "also fetch remote sources (git, URLs) to list their files")
+	mf := addManifestFlags(fs)
+	fs.Parse(args)
+
+	opts, err := mf.options()
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("no definition path given, use -f")
+	}
+
+	paths, err := collectPaths(*file, &opts)
+	if err != nil {
+		return err
+	}
+	manifests, err := sortManifests(loadManifests(paths, opts))
+	if err != nil {
+		return err
+	}
+
+	for i, m := range manifests {
+		if i > 0 {
+			logf("---\n")
+		}
+		logf("# Source: %s\n", m.Path)
+		content, err := marshalManifest(m.Config)
+		if err != nil {
+			return err
+		}
+		logf("%s", content)
+		if c, ok := m.Config.(Documents); ok {
+			renderSources(c, m.Path, *resolveRemote)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	jsonPathToken       = regexp.MustCompile(`[^.\[\]]+|\[\d+\]`)
+	itemPlaceholder     = regexp.MustCompile(`\$\{([^}]*)\}`)
+	unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+func evalJsonPath(value interface{}, expr string) interface{} {
+	expr = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(expr), "$"), ".")
+	for _, token := range jsonPathToken.FindAllString(expr, -1) {
+		if strings.HasPrefix(token, "[") {
+			index, _ := strconv.Atoi(strings.Trim(token, "[]"))
+			list, ok := value.([]interface{})
+			if !ok || index >= len(list) {
+				return nil
+			}
+			value = list[index]
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[token]
+	}
+	return value
+}
+
+func jsonValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func renderApiItem(template string, item interface{}) string {
+	return itemPlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		return jsonValueString(evalJsonPath(item, itemPlaceholder.FindStringSubmatch(match)[1]))
+	})
+}
+
+func nextApiPage(current *url.URL, source DocumentSource, next string) string {
+	if next == "" {
+		return ""
+	}
+	if strings.Contains(next, "://") || strings.HasPrefix(next, "/") || strings.HasPrefix(next, "?") {
+		resolved, err := current.Parse(next)
+		if err != nil {
+			return ""
+		}
+		return resolved.String()
+	}
+	param := source.NextPageParam
+	if param == "" {
+		param = "cursor"
+	}
+	nextUrl := *current
+	query := nextUrl.Query()
+	query.Set(param, next)
+	nextUrl.RawQuery = query.Encode()
+	return nextUrl.String()
+}
+
+func handleApiSource(source DocumentSource) ([]string, string, error) {
+	headers, err := urlHeaders(source)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, ok := headers["Accept"]; !ok {
+		headers["Accept"] = "application/json"
+	}
+	tempDir, err := os.MkdirTemp("", "oictl_api_")
+	if err != nil {
+		return nil, "", err
+	}
+	fail := func(err error) ([]string, string, error) {
+		os.RemoveAll(tempDir)
+		return nil, "", err
+	}
+
+	base := unsafeFilenameChars.ReplaceAllString(path.Base(strings.SplitN(source.Source, "?", 2)[0]), "_")
+	var files []string
+	seen := make(map[string]bool)
+	for pageUrl := source.Source; pageUrl != "" && !seen[pageUrl]; {
+		seen[pageUrl] = true
+		current, err := url.Parse(pageUrl)
+		if err != nil {
+			return fail(err)
+		}
+		content, err := fetchUrlContent(pageUrl, headers)
+		if err != nil {
+			return fail(err)
+		}
+		var page interface{}
+		if err := json.Unmarshal([]byte(content), &page); err != nil {
+			return fail(fmt.Errorf("invalid JSON from %s: %v", pageUrl, err))
+		}
+
+		items := page
+		if source.ItemsJsonPath != "" {
+			items = evalJsonPath(page, source.ItemsJsonPath)
+		}
+		list, ok := items.([]interface{})
+		if !ok {
+			return fail(fmt.Errorf("%s: itemsJSONPath %q does not select a list", pageUrl, source.ItemsJsonPath))
+		}
+		for _, item := range list {
+			id := strconv.Itoa(len(files) + 1)
+			if source.IdJsonPath != "" {
+				if value := jsonValueString(evalJsonPath(item, source.IdJsonPath)); value != "" {
+					id = unsafeFilenameChars.ReplaceAllString(value, "_")
+				}
+			}
+			var document []byte
+			name := fmt.Sprintf("%s-%s.md", base, id)
+			if source.Template != "" {
+				document = []byte(renderApiItem(source.Template, item))
+			} else {
+				name = fmt.Sprintf("%s-%s.json", base, id)
+				document, _ = json.MarshalIndent(item, "", "  ")
+			}
+			target := filepath.Join(tempDir, name)
+			if err := os.WriteFile(target, document, 0644); err != nil {
+				return fail(err)
+			}
+			files = append(files, target)
+		}
+		logf("\rAPI items fetched: %d", len(files))
+
+		if source.NextPageJsonPath == "" {
+			break
+		}
+		pageUrl = nextApiPage(current, source, jsonValueString(evalJsonPath(page, source.NextPageJsonPath)))
+	}
+	if len(files) > 0 {
+		logf("\n")
+	}
+	return files, tempDir, nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const restoredSuffix = "-restored"
+
+type restoreSummary struct {
+	created, updated, renamed, skipped, failed int
+}
+
+func (s *restoreSummary) String() string {
+	return fmt.Sprintf("%d created, %d updated, %d renamed, %d skipped, %d failed", s.created, s.updated, s.renamed, s.skipped, s.failed)
+}
+
+func readArchive(archivePath string) (map[string][]byte, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	entries := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		entries[path.Clean(header.Name)] = content
+	}
+	return entries, nil
+}
+
+func archiveEntries(entries map[string][]byte, dir string) []string {
+	var names []string
+	for name := range entries {
+		if strings.HasPrefix(name, dir+"/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func renamedFilename(filename string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + restoredSuffix + ext
+}
+
+func restoreDocuments(entries map[string][]byte, policy string, summary *restoreSummary) error {
+	existing, err := getDocs(TOKEN)
+	if err != nil {
+		return err
+	}
+	existingNames := make(map[string]bool)
+	for _, doc := range existing {
+		existingNames[doc.Name] = true
+	}
+
+	for _, name := range archiveEntries(entries, "documents") {
+		var manifest Documents
+		if err := yaml.Unmarshal(entries[name], &manifest); err != nil {
+			logf("Error reading %s: %v\n", name, err)
+			summary.failed++
+			continue
+		}
+		for _, source := range manifest.Spec.Sources {
+			docName := path.Base(source.Source)
+			content, ok := entries[path.Join(path.Dir(name), source.Source)]
+			if !ok {
+				logf("Skipped document %s: file contents are not part of the backup\n", docName)
+				summary.skipped++
+				continue
+			}
+
+			filename := docName
+			var opts documentOptions
+			if existingNames[docName] {
+				switch policy {
+				case "skip":
+					summary.skipped++
+					continue
+				case "overwrite":
+					opts.Replace = docName
+				case "rename":
+					filename = renamedFilename(filename)
+				}
+			}
+
+			tags := manifest.sourceTags(source)
+			if manifest.Metadata.Name == "untagged" {
+				tags = tags[1:]
+			}
+			tempFile, err := writeTempFile(content)
+			if err != nil {
+				return err
+			}
+			_, err = createDocument(tempFile, BASE_URL, tags, filename, manifest.Metadata, opts)
+			os.Remove(tempFile)
+			if err != nil {
+				logf("Error restoring document %s: %v\n", docName, err)
+				summary.failed++
+				continue
+			}
+			countRestored(summary, existingNames[docName], policy)
+		}
+	}
+	return nil
+}
+
+func writeTempFile(content []byte) (string, error) {
+	file, err := os.CreateTemp("", "oictl_restore_")
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+func countRestored(summary *restoreSummary, exists bool, policy string) {
+	switch {
+	case !exists:
+		summary.created++
+	case policy == "overwrite":
+		summary.updated++
+	default:
+		summary.renamed++
+	}
+}
+
+func restoreModels(entries map[string][]byte, policy string, summary *restoreSummary) error {
+	existing, err := getModels()
+	if err != nil {
+		return err
+	}
+	existingIDs := make(map[string]bool)
+	for _, model := range existing {
+		existingIDs[model.ID] = true
+	}
+
+	for _, name := range archiveEntries(entries, "models") {
+		var model Model
+		if err := yaml.Unmarshal(entries[name], &model); err != nil {
+			logf("Error reading %s: %v\n", name, err)
+			summary.failed++
+			continue
+		}
+
+		exists := existingIDs[model.Metadata.Name]
+		if exists {
+			switch policy {
+			case "skip":
+				summary.skipped++
+				continue
+			case "overwrite":
+				err = updateModel(model)
+			case "rename":
+				model.Metadata.Name += restoredSuffix
+				err = processModel(model)
+			}
+		} else {
+			err = processModel(model)
+		}
+		if err != nil {
+			logf("Error restoring model %s: %v\n", model.Metadata.Name, err)
+			summary.failed++
+			continue
+		}
+		countRestored(summary, exists, policy)
+	}
+	return nil
+}
+
+func restoreManifests(entries map[string][]byte, dir, idField, listEndpoint string, policy string, summary *restoreSummary) error {
+	existing, err := getRawList(listEndpoint)
+	if err != nil {
+		return err
+	}
+	existingIDs := make(map[string]bool)
+	for _, item := range rawItems(existing) {
+		if id, ok := item[idField].(string); ok {
+			existingIDs[strings.TrimPrefix(id, "/")] = true
+		}
+	}
+
+	for _, name := range archiveEntries(entries, dir) {
+		m, err := parseManifest(name, entries[name])
+		if err != nil {
+			logf("Error reading %s: %v\n", name, err)
+			summary.failed++
+			continue
+		}
+		id := m.Metadata.Name
+
+		exists := existingIDs[id]
+		if exists {
+			switch policy {
+			case "skip":
+				summary.skipped++
+				continue
+			case "rename":
+				m = renamedManifest(m, id+strings.ReplaceAll(restoredSuffix, "-", "_"))
+			}
+		}
+		if err := applyResource(m); err != nil {
+			logf("Error restoring %s %s: %v\n", dir, id, err)
+			summary.failed++
+			continue
+		}
+		countRestored(summary, exists, policy)
+	}
+	return nil
+}
+
+func renamedManifest(m manifest, name string) manifest {
+	switch c := m.Config.(type) {
+	case Prompt:
+		c.Metadata.Name, c.Spec.Command = name, ""
+		m.Config = c
+	case Tool:
+		c.Metadata.Name = name
+		m.Config = c
+	}
+	m.Metadata.Name = name
+	return m
+}
+
+func restoreBackup(archivePath, policy string) error {
+	entries, err := readArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	if _, ok := entries["backup.yaml"]; !ok {
+		return fmt.Errorf("%s is not an oictl backup archive", archivePath)
+	}
+
+	documents := &restoreSummary{}
+	if err := restoreDocuments(entries, policy, documents); err != nil {
+		return err
+	}
+	logf("Documents: %s\n", documents)
+
+	prompts := &restoreSummary{}
+	if err := restoreManifests(entries, "prompts", "command", "/api/v1/prompts/", policy, prompts); err != nil {
+		return err
+	}
+	logf("Prompts: %s\n", prompts)
+
+	tools := &restoreSummary{}
+	if err := restoreManifests(entries, "tools", "id", "/api/v1/tools/", policy, tools); err != nil {
+		return err
+	}
+	logf("Tools: %s\n", tools)
+
+	models := &restoreSummary{}
+	if err := restoreModels(entries, policy, models); err != nil {
+		return err
+	}
+	logf("Models: %s\n", models)
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	server := fs.String("server", "", "URL of the server to restore to (default "+BASE_URL+")")
+	policy := fs.String("on-conflict", "skip", "what to do with resources that already exist: skip, overwrite or rename")
+	positional := parseArgs(fs, args)
+
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: oictl restore <backup.tar.gz> [--server url] [--on-conflict skip|overwrite|rename]")
+	}
+	switch *policy {
+	case "skip", "overwrite", "rename":
+	default:
+		return fmt.Errorf("unknown conflict policy %s", *policy)
+	}
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+	if *server != "" {
+		BASE_URL = strings.TrimSuffix(*server, "/")
+	}
+
+	return restoreBackup(positional[0], *policy)
+}
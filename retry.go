@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxRateLimitWaits = 10
+
+type retryPolicy struct {
+	Retries       int
+	Backoff       time.Duration
+	MaxBackoff    time.Duration
+	MaxRetryAfter time.Duration
+	Statuses      []int
+}
+
+var (
+	httpRetryPolicy = loadRetryPolicy()
+	requestLimiter  = &rateLimiter{}
+)
+
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *rateLimiter) setMaxRate(perSecond float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval = 0
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+}
+
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.next) {
+		l.next = until
+	}
+}
+
+func (l *rateLimiter) wait(req *http.Request) error {
+	l.mu.Lock()
+	start := time.Now()
+	if l.next.After(start) {
+		start = l.next
+	}
+	if l.interval > 0 {
+		l.next = start.Add(l.interval)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-time.After(time.Until(start)):
+		return nil
+	}
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func loadRetryPolicy() retryPolicy {
+	policy := retryPolicy{
+		Retries:       3,
+		Backoff:       500 * time.Millisecond,
+		MaxBackoff:    30 * time.Second,
+		MaxRetryAfter: 5 * time.Minute,
+		Statuses:      []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+	if value := os.Getenv("OICTL_RETRIES"); value != "" {
+		if retries, err := strconv.Atoi(value); err == nil && retries >= 0 {
+			policy.Retries = retries
+		} else {
+			errorf("Ignoring invalid OICTL_RETRIES %q\n", value)
+		}
+	}
+	if value := os.Getenv("OICTL_RETRY_BACKOFF"); value != "" {
+		if backoff, err := time.ParseDuration(value); err == nil && backoff > 0 {
+			policy.Backoff = backoff
+		} else {
+			errorf("Ignoring invalid OICTL_RETRY_BACKOFF %q\n", value)
+		}
+	}
+	if value := os.Getenv("OICTL_RETRY_MAX_BACKOFF"); value != "" {
+		if backoff, err := time.ParseDuration(value); err == nil && backoff > 0 {
+			policy.MaxBackoff = backoff
+		} else {
+			errorf("Ignoring invalid OICTL_RETRY_MAX_BACKOFF %q\n", value)
+		}
+	}
+	if value := os.Getenv("OICTL_MAX_RETRY_AFTER"); value != "" {
+		if wait, err := time.ParseDuration(value); err == nil && wait > 0 {
+			policy.MaxRetryAfter = wait
+		} else {
+			errorf("Ignoring invalid OICTL_MAX_RETRY_AFTER %q\n", value)
+		}
+	}
+	if value, ok := os.LookupEnv("OICTL_RETRY_STATUS"); ok {
+		var statuses []int
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+			status, err := strconv.Atoi(field)
+			if err != nil {
+				errorf("Ignoring invalid status %q in OICTL_RETRY_STATUS\n", field)
+				continue
+			}
+			statuses = append(statuses, status)
+		}
+		policy.Statuses = statuses
+	}
+	return policy
+}
+
+func (p retryPolicy) retryable(status int) bool {
+	for _, s := range p.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (p retryPolicy) retryAfter(resp *http.Response) (time.Duration, bool, error) {
+	after, ok := retryAfter(resp)
+	if ok && after > p.MaxRetryAfter {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return 0, false, fmt.Errorf("server answered %s with Retry-After %s, longer than the %s allowed by OICTL_MAX_RETRY_AFTER", resp.Status, after.Round(time.Second), p.MaxRetryAfter)
+	}
+	return after, ok, nil
+}
+
+func (p retryPolicy) delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 0; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+type retryableKey struct{}
+
+func markRetryable(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), retryableKey{}, true))
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	retryable, _ := req.Context().Value(retryableKey{}).(bool)
+	return retryable
+}
+
+type retryTransport struct {
+	next http.RoundTripper
+}
+
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := httpRetryPolicy
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	retries, rateLimitWaits := 0, 0
+	for sent := 0; ; sent++ {
+		attemptReq := req
+		if sent > 0 {
+			attemptReq = req.Clone(req.Context())
+			if req.Body != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		if err := requestLimiter.wait(attemptReq); err != nil {
+			return nil, err
+		}
+		resp, err := t.next.RoundTrip(attemptReq)
+		if !rewindable || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		var delay time.Duration
+		switch {
+		case err == nil && resp.StatusCode == http.StatusTooManyRequests && rateLimitWaits < maxRateLimitWaits:
+			rateLimitWaits++
+			delay = policy.delay(rateLimitWaits - 1)
+			after, ok, err := policy.retryAfter(resp)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				delay = after
+			}
+			logf("\nRate limited by %s, pausing requests for %s\n", req.URL.Host, delay.Round(time.Second))
+			requestLimiter.pause(delay)
+			delay = 0
+		case retries >= policy.Retries || !idempotent(req):
+			return resp, err
+		case err != nil:
+			delay = policy.delay(retries)
+			retries++
+			debugf("%s %s failed: %v, retrying in %s\n", req.Method, req.URL.Redacted(), err, delay.Round(time.Millisecond))
+		case policy.retryable(resp.StatusCode):
+			delay = policy.delay(retries)
+			after, ok, err := policy.retryAfter(resp)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				delay = after
+			}
+			retries++
+			debugf("%s %s returned %s, retrying in %s\n", req.Method, req.URL.Redacted(), resp.Status, delay.Round(time.Millisecond))
+		default:
+			return resp, nil
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func resolveSecretRef(ref string) (string, error) {
+	scheme, location, ok := strings.Cut(ref, ":")
+	if !ok {
+		return "", fmt.Errorf("invalid secretRef %q, expected env:NAME, file:PATH or vault:PATH#FIELD", ref)
+	}
+
+	var value string
+	switch scheme {
+	case "env":
+		var set bool
+		if value, set = os.LookupEnv(location); !set {
+			return "", fmt.Errorf("secretRef %s: environment variable is not set", ref)
+		}
+	case "file":
+		content, err := os.ReadFile(location)
+		if err != nil {
+			return "", fmt.Errorf("secretRef %s: %v", ref, err)
+		}
+		value = strings.TrimRight(string(content), "\r\n")
+	case "vault":
+		path, field, ok := strings.Cut(location, "#")
+		if !ok || field == "" {
+			return "", fmt.Errorf("invalid secretRef %q, expected vault:PATH#FIELD", ref)
+		}
+		output, err := exec.Command("vault", "kv", "get", "-field="+field, path).Output()
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				return "", fmt.Errorf("secretRef %s: %s", ref, strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return "", fmt.Errorf("secretRef %s: %v", ref, err)
+		}
+		value = strings.TrimRight(string(output), "\r\n")
+	default:
+		return "", fmt.Errorf("secretRef %s: unknown secret source %s", ref, scheme)
+	}
+	registerSecret(value)
+	return value, nil
+}
+
+func replaceSecretRefs(node *yaml.Node, resolve bool) error {
+	if node.Kind == yaml.MappingNode && len(node.Content) == 2 && node.Content[0].Value == "secretRef" && node.Content[1].Kind == yaml.ScalarNode {
+		ref := node.Content[1].Value
+		value := fmt.Sprintf("<secretRef:%s>", ref)
+		if resolve {
+			var err error
+			if value, err = resolveSecretRef(ref); err != nil {
+				return err
+			}
+		}
+		*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+		return nil
+	}
+	for _, child := range node.Content {
+		if err := replaceSecretRefs(child, resolve); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveSecrets(content []byte, resolve bool) ([]byte, error) {
+	if !strings.Contains(string(content), "secretRef") {
+		return content, nil
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+	if err := replaceSecretRefs(&root, resolve); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(&root)
+}
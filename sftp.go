@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func isSftpSource(source string) bool {
+	return strings.HasPrefix(source, "sftp://")
+}
+
+func handleSftpSource(source DocumentSource) ([]string, string, error) {
+	u, err := url.Parse(source.Source)
+	if err != nil || u.Host == "" {
+		return nil, "", fmt.Errorf("invalid source %s, expected sftp://user@host/path", source.Source)
+	}
+	remotePath := strings.TrimPrefix(u.Path, "/~/")
+	if remotePath == "" {
+		remotePath = "."
+	}
+	remotePaths := []string{remotePath}
+	if len(source.Dir) > 0 {
+		remotePaths = nil
+		for _, dir := range source.Dir {
+			remotePaths = append(remotePaths, path.Join(remotePath, dir))
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "oictl_sftp_")
+	if err != nil {
+		return nil, "", err
+	}
+	var batch strings.Builder
+	for i, p := range remotePaths {
+		fmt.Fprintf(&batch, "get -R %s %s\n", strconv.Quote(p), strconv.Quote(filepath.Join(tempDir, strconv.Itoa(i))))
+	}
+
+	args := []string{"-b", "-", "-o", "BatchMode=yes"}
+	if u.Port() != "" {
+		args = append(args, "-P", u.Port())
+	}
+	if source.IdentityFile != "" {
+		args = append(args, "-i", source.IdentityFile)
+	}
+	host := u.Hostname()
+	if u.User != nil {
+		host = u.User.Username() + "@" + host
+	}
+	cmd := exec.Command("sftp", append(args, host)...)
+	cmd.Stdin = strings.NewReader(batch.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", fmt.Errorf("sftp %s: %v: %s", host, err, strings.TrimSpace(string(output)))
+	}
+
+	var files []string
+	ignore, err := sourceIgnoreMatcher(tempDir, source)
+	if err == nil {
+		files, err = traverseDirectory(tempDir, source, ignore)
+	}
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, "", err
+	}
+	return files, tempDir, nil
+}
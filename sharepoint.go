@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const graphUrl = "https://graph.microsoft.com/v1.0"
+
+type graphItem struct {
+	ID     string    `json:"id"`
+	Name   string    `json:"name"`
+	Folder *struct{} `json:"folder"`
+	File   *struct{} `json:"file"`
+}
+
+func graphToken(source DocumentSource) (string, error) {
+	if source.Token != "" {
+		registerSecret(source.Token)
+		return source.Token, nil
+	}
+	if source.TenantID == "" || source.ClientID == "" || source.ClientSecret == "" {
+		return "", fmt.Errorf("sharepoint source %s needs a token or tenantId, clientId and clientSecret", source.Source)
+	}
+	registerSecret(source.ClientSecret)
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {source.ClientID},
+		"client_secret": {source.ClientSecret},
+		"scope":         {"https://graph.microsoft.com/.default"},
+	}
+	resp, err := httpClient.PostForm(fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", url.PathEscape(source.TenantID)), form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to get Microsoft Graph token: %s - %s", resp.Status, string(respBody))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	registerSecret(token.AccessToken)
+	return token.AccessToken, nil
+}
+
+func graphRequest(token, rawUrl string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GET %s: %s - %s", rawUrl, resp.Status, string(respBody))
+	}
+	return resp, nil
+}
+
+func graphGet(token, rawUrl string, out interface{}) error {
+	resp, err := graphRequest(token, rawUrl)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func graphEscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func resolveSharepointFolder(token, source string) (string, string, error) {
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("invalid sharepoint source %s", source)
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	sitePath := ""
+	if len(segments) >= 2 && (segments[0] == "sites" || segments[0] == "teams" || segments[0] == "personal") {
+		sitePath = segments[0] + "/" + segments[1]
+	}
+	siteUrl := fmt.Sprintf("%s/sites/%s", graphUrl, u.Host)
+	if sitePath != "" {
+		siteUrl = fmt.Sprintf("%s:/%s", siteUrl, graphEscapePath(sitePath))
+	}
+	var site struct {
+		ID string `json:"id"`
+	}
+	if err := graphGet(token, siteUrl, &site); err != nil {
+		return "", "", err
+	}
+
+	var drives struct {
+		Value []struct {
+			ID     string `json:"id"`
+			WebUrl string `json:"webUrl"`
+		} `json:"value"`
+	}
+	if err := graphGet(token, fmt.Sprintf("%s/sites/%s/drives", graphUrl, site.ID), &drives); err != nil {
+		return "", "", err
+	}
+	fullPath := strings.TrimSuffix(u.Path, "/")
+	driveID, drivePath := "", ""
+	for _, drive := range drives.Value {
+		driveUrl, err := url.Parse(drive.WebUrl)
+		if err != nil {
+			continue
+		}
+		p := strings.TrimSuffix(driveUrl.Path, "/")
+		if (fullPath == p || strings.HasPrefix(fullPath, p+"/")) && len(p) > len(drivePath) {
+			driveID, drivePath = drive.ID, p
+		}
+	}
+	if driveID == "" {
+		return "", "", fmt.Errorf("no document library found for %s", source)
+	}
+	return driveID, strings.Trim(strings.TrimPrefix(fullPath, drivePath), "/"), nil
+}
+
+func listSharepointFolder(token, driveID, folder string) ([]string, map[string]string, error) {
+	next := fmt.Sprintf("%s/drives/%s/root/children", graphUrl, driveID)
+	if folder != "" {
+		next = fmt.Sprintf("%s/drives/%s/root:/%s:/children", graphUrl, driveID, graphEscapePath(folder))
+	}
+
+	var paths []string
+	ids := make(map[string]string)
+	for next != "" {
+		var page struct {
+			Value    []graphItem `json:"value"`
+			NextLink string      `json:"@odata.nextLink"`
+		}
+		if err := graphGet(token, next, &page); err != nil {
+			return nil, nil, err
+		}
+		for _, item := range page.Value {
+			itemPath := path.Join(folder, item.Name)
+			if item.Folder != nil {
+				nested, nestedIDs, err := listSharepointFolder(token, driveID, itemPath)
+				if err != nil {
+					return nil, nil, err
+				}
+				paths = append(paths, nested...)
+				for p, id := range nestedIDs {
+					ids[p] = id
+				}
+			} else if item.File != nil {
+				paths = append(paths, itemPath)
+				ids[itemPath] = item.ID
+			}
+		}
+		next = page.NextLink
+	}
+	return paths, ids, nil
+}
+
+func downloadSharepointFile(token, driveID, itemID, target string) error {
+	resp, err := graphRequest(token, fmt.Sprintf("%s/drives/%s/items/%s/content", graphUrl, driveID, itemID))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
+
+func handleSharepointSource(source DocumentSource) ([]string, string, error) {
+	token, err := graphToken(source)
+	if err != nil {
+		return nil, "", err
+	}
+	driveID, root, err := resolveSharepointFolder(token, source.Source)
+	if err != nil {
+		return nil, "", err
+	}
+	folders := []string{root}
+	if len(source.Dir) > 0 {
+		folders = nil
+		for _, dir := range source.Dir {
+			folders = append(folders, strings.Trim(path.Join(root, dir), "/"))
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "oictl_sharepoint_")
+	if err != nil {
+		return nil, "", err
+	}
+	var files []string
+	for _, folder := range folders {
+		paths, ids, err := listSharepointFolder(token, driveID, folder)
+		if err != nil {
+			os.RemoveAll(tempDir)
+			return nil, "", err
+		}
+		for _, p := range paths {
+			if len(source.Extensions) > 0 && !hasExtension(p, source.Extensions) {
+				continue
+			}
+			relative := strings.TrimPrefix(p, root)
+			target := filepath.Join(tempDir, filepath.FromSlash(path.Clean("/"+relative)))
+			if err := downloadSharepointFile(token, driveID, ids[p], target); err != nil {
+				os.RemoveAll(tempDir)
+				return nil, "", err
+			}
+			files = append(files, target)
+		}
+	}
+	return files, tempDir, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var siteSkippedPages = []string{"search.html", "genindex.html", "py-modindex.html", "404.html"}
+
+func siteBasePath(u *url.URL) string {
+	return u.Path[:strings.LastIndex(u.Path, "/")+1]
+}
+
+func handleSiteUrlSource(source DocumentSource) ([]sourceFile, string, error) {
+	seed, err := url.Parse(source.Source)
+	if err != nil {
+		return nil, "", err
+	}
+	headers, err := urlHeaders(source)
+	if err != nil {
+		return nil, "", err
+	}
+	sitemap := *seed
+	sitemap.Path = siteBasePath(seed) + "sitemap.xml"
+	if _, err := fetchUrlContent(sitemap.String(), headers); err == nil {
+		sitemapSource := source
+		sitemapSource.Source = sitemap.String()
+		if len(sitemapSource.Dir) == 0 {
+			sitemapSource.Dir = []string{siteBasePath(seed)}
+		}
+		return handleSitemapSource(sitemapSource)
+	}
+
+	if source.MaxDepth == 0 {
+		source.MaxDepth = 10
+	}
+	return handleCrawlSource(source)
+}
+
+func sitePageFile(file sourceFile, root, tempDir string) ([]sourceFile, error) {
+	if containsString(siteSkippedPages, filepath.Base(file.Path)) {
+		return nil, nil
+	}
+	content, err := os.ReadFile(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	relative, err := filepath.Rel(root, file.Path)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		relative = filepath.Base(file.Path)
+	}
+	relative = strings.TrimSuffix(filepath.ToSlash(relative), path.Ext(relative))
+	relative = strings.TrimSuffix(strings.TrimSuffix(relative, "index"), "/")
+	if relative == "" {
+		relative = "index"
+	}
+
+	title, text := htmlToMarkdown(string(content), "")
+	if title == "" {
+		title = relative
+	}
+	name := strings.ReplaceAll(relative, "/", "_") + ".md"
+	target := filepath.Join(tempDir, safeArchiveName(name))
+	if err := os.WriteFile(target, []byte("# "+title+"\n\n"+text+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return []sourceFile{{Path: target, Filename: name, Tags: file.Tags}}, nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type sheet struct {
+	Name string
+	Rows [][]string
+}
+
+func readZipFile(archive *zip.ReadCloser, name string) ([]byte, error) {
+	for _, f := range archive.File {
+		if f.Name == name {
+			r, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return io.ReadAll(r)
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func cellColumn(ref string) int {
+	col := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A') + 1
+	}
+	return col - 1
+}
+
+func trimSheet(rows [][]string) [][]string {
+	for i, row := range rows {
+		for len(row) > 0 && strings.TrimSpace(row[len(row)-1]) == "" {
+			row = row[:len(row)-1]
+		}
+		rows[i] = row
+	}
+	for len(rows) > 0 && len(rows[len(rows)-1]) == 0 {
+		rows = rows[:len(rows)-1]
+	}
+	return rows
+}
+
+func readXlsx(filePath string) ([]sheet, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var sharedStrings []string
+	if content, err := readZipFile(archive, "xl/sharedStrings.xml"); err == nil {
+		var sst struct {
+			Items []struct {
+				Text string `xml:"t"`
+				Runs []struct {
+					Text string `xml:"t"`
+				} `xml:"r"`
+			} `xml:"si"`
+		}
+		if err := xml.Unmarshal(content, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			text := item.Text
+			for _, run := range item.Runs {
+				text += run.Text
+			}
+			sharedStrings = append(sharedStrings, text)
+		}
+	}
+
+	content, err := readZipFile(archive, "xl/workbook.xml")
+	if err != nil {
+		return nil, fmt.Errorf("not an xlsx workbook: %v", err)
+	}
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(content, &workbook); err != nil {
+		return nil, err
+	}
+	content, err = readZipFile(archive, "xl/_rels/workbook.xml.rels")
+	if err != nil {
+		return nil, err
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.Unmarshal(content, &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string)
+	for _, rel := range rels.Relationships {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	var sheets []sheet
+	for _, s := range workbook.Sheets {
+		content, err := readZipFile(archive, targets[s.ID])
+		if err != nil {
+			return nil, fmt.Errorf("sheet %s: %v", s.Name, err)
+		}
+		var data struct {
+			Rows []struct {
+				Cells []struct {
+					Ref    string `xml:"r,attr"`
+					Type   string `xml:"t,attr"`
+					Value  string `xml:"v"`
+					Inline string `xml:"is>t"`
+				} `xml:"c"`
+			} `xml:"sheetData>row"`
+		}
+		if err := xml.Unmarshal(content, &data); err != nil {
+			return nil, fmt.Errorf("sheet %s: %v", s.Name, err)
+		}
+
+		var rows [][]string
+		for _, r := range data.Rows {
+			var row []string
+			for _, c := range r.Cells {
+				value := c.Value
+				switch c.Type {
+				case "s":
+					if i, err := strconv.Atoi(c.Value); err == nil && i < len(sharedStrings) {
+						value = sharedStrings[i]
+					}
+				case "inlineStr":
+					value = c.Inline
+				case "b":
+					value = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+				}
+				col := len(row)
+				if c.Ref != "" {
+					col = cellColumn(c.Ref)
+				}
+				for len(row) <= col {
+					row = append(row, "")
+				}
+				row[col] = value
+			}
+			rows = append(rows, row)
+		}
+		sheets = append(sheets, sheet{Name: s.Name, Rows: trimSheet(rows)})
+	}
+	return sheets, nil
+}
+
+func odsAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func readOds(filePath string) ([]sheet, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	content, err := readZipFile(archive, "content.xml")
+	if err != nil {
+		return nil, fmt.Errorf("not an ods spreadsheet: %v", err)
+	}
+
+	var sheets []sheet
+	var current *sheet
+	var row []string
+	var cell strings.Builder
+	rowRepeat, cellRepeat, inCell, paragraphs := 1, 1, false, 0
+	decoder := xml.NewDecoder(strings.NewReader(string(content)))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "table":
+				sheets = append(sheets, sheet{Name: odsAttr(t, "name")})
+				current = &sheets[len(sheets)-1]
+			case "table-row":
+				row = nil
+				rowRepeat, _ = strconv.Atoi(odsAttr(t, "number-rows-repeated"))
+			case "table-cell", "covered-table-cell":
+				cell.Reset()
+				inCell, paragraphs = true, 0
+				cellRepeat, _ = strconv.Atoi(odsAttr(t, "number-columns-repeated"))
+				if value := odsAttr(t, "value"); value != "" && t.Name.Local == "table-cell" {
+					cell.WriteString(value)
+					inCell = false
+				}
+			case "p":
+				if inCell && paragraphs > 0 {
+					cell.WriteString("\n")
+				}
+				paragraphs++
+			}
+		case xml.CharData:
+			if inCell {
+				cell.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "table-cell", "covered-table-cell":
+				inCell = false
+				if cellRepeat < 1 || (cell.Len() == 0 && cellRepeat > 1) {
+					cellRepeat = 1
+				}
+				for i := 0; i < cellRepeat; i++ {
+					row = append(row, cell.String())
+				}
+			case "table-row":
+				if current == nil {
+					continue
+				}
+				if rowRepeat < 1 || (len(trimSheet([][]string{row})) == 0 && rowRepeat > 1) {
+					rowRepeat = 1
+				}
+				for i := 0; i < rowRepeat; i++ {
+					current.Rows = append(current.Rows, append([]string(nil), row...))
+				}
+			case "table":
+				current.Rows = trimSheet(current.Rows)
+				current = nil
+			}
+		}
+	}
+	return sheets, nil
+}
+
+func markdownCell(value string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(value), "|", "\\|"), "\n", "<br>")
+}
+
+func sheetMarkdown(filename string, s sheet) string {
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "# %s - %s\n\n", filename, s.Name)
+	width := 0
+	for _, row := range s.Rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	for i, row := range s.Rows {
+		cells := make([]string, width)
+		for col := range cells {
+			if col < len(row) {
+				cells[col] = markdownCell(row[col])
+			}
+		}
+		fmt.Fprintf(&doc, "| %s |\n", strings.Join(cells, " | "))
+		if i == 0 {
+			fmt.Fprintf(&doc, "|%s\n", strings.Repeat(" --- |", width))
+		}
+	}
+	return doc.String()
+}
+
+func extractSpreadsheet(file sourceFile, rowsPerDocument int, tempDir string) ([]sourceFile, error) {
+	var sheets []sheet
+	var err error
+	if strings.EqualFold(filepath.Ext(file.Filename), ".ods") {
+		sheets, err = readOds(file.Path)
+	} else {
+		sheets, err = readXlsx(file.Path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", file.Filename, err)
+	}
+
+	base := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	var files []sourceFile
+	for i, s := range sheets {
+		if len(s.Rows) == 0 {
+			continue
+		}
+		name := fmt.Sprintf("%s - %s.md", base, s.Name)
+		target := filepath.Join(tempDir, fmt.Sprintf("sheet-%d-%s", i, safeArchiveName(name)))
+		if rowsPerDocument > 0 {
+			name = fmt.Sprintf("%s - %s.csv", base, s.Name)
+			target = strings.TrimSuffix(target, ".md") + ".csv"
+			f, err := os.Create(target)
+			if err != nil {
+				return nil, err
+			}
+			writer := csv.NewWriter(f)
+			writer.WriteAll(s.Rows)
+			f.Close()
+			if err := writer.Error(); err != nil {
+				return nil, err
+			}
+			rows, err := splitCsvFile(sourceFile{Path: target, Filename: name, Tags: file.Tags}, rowsPerDocument, tempDir)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, rows...)
+			continue
+		}
+		if err := os.WriteFile(target, []byte(sheetMarkdown(file.Filename, s)), 0644); err != nil {
+			return nil, err
+		}
+		files = append(files, sourceFile{Path: target, Filename: name, Tags: file.Tags})
+	}
+	return files, nil
+}
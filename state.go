@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type stateEntry struct {
+	Tag        string    `json:"tag"`
+	Source     string    `json:"source"`
+	Path       string    `json:"path,omitempty"`
+	Filename   string    `json:"filename"`
+	ModTime    time.Time `json:"mtime"`
+	Size       int64     `json:"size"`
+	Sha256     string    `json:"sha256"`
+	Collection string    `json:"collection,omitempty"`
+	Document   string    `json:"document,omitempty"`
+}
+
+func (e stateEntry) key() string {
+	return strings.Join([]string{e.Tag, e.Source, e.Path, e.Filename}, "\x00")
+}
+
+func (e stateEntry) displayName() string {
+	name := e.Path
+	if name == "" {
+		name = e.Source
+	}
+	if path.Base(name) != e.Filename {
+		name += " (" + e.Filename + ")"
+	}
+	return name
+}
+
+type syncState struct {
+	Server    string       `json:"server"`
+	Documents []stateEntry `json:"documents"`
+
+	path    string
+	entries map[string]int
+	seen    map[string]bool
+}
+
+func defaultStatePath(server string) string {
+	name := server
+	if u, err := url.Parse(server); err == nil && u.Host != "" {
+		name = u.Host + u.Path
+	}
+	name = strings.Trim(unsafeFilenameChars.ReplaceAllString(name, "_"), "_")
+	return filepath.Join(filepath.Dir(configPath()), "state", name+".json")
+}
+
+func loadState(path, server string) (*syncState, error) {
+	state := &syncState{Server: server, path: path}
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, state); err != nil {
+			return nil, fmt.Errorf("failed to parse state file %s: %v", path, err)
+		}
+		if state.Server != server {
+			return nil, fmt.Errorf("state file %s belongs to %s, not %s", path, state.Server, server)
+		}
+	}
+	state.entries = make(map[string]int)
+	state.seen = make(map[string]bool)
+	for i, entry := range state.Documents {
+		state.entries[entry.key()] = i
+	}
+	return state, nil
+}
+
+func (s *syncState) find(key string) (stateEntry, bool) {
+	i, ok := s.entries[key]
+	if !ok {
+		return stateEntry{}, false
+	}
+	return s.Documents[i], true
+}
+
+func (s *syncState) record(entry stateEntry) {
+	key := entry.key()
+	s.seen[key] = true
+	if i, ok := s.entries[key]; ok {
+		s.Documents[i] = entry
+		return
+	}
+	s.entries[key] = len(s.Documents)
+	s.Documents = append(s.Documents, entry)
+}
+
+func (s *syncState) markSeen(key string) {
+	s.seen[key] = true
+}
+
+func (s *syncState) removed(tag string) []stateEntry {
+	var removed []stateEntry
+	for _, entry := range s.Documents {
+		if entry.Tag == tag && !s.seen[entry.key()] {
+			removed = append(removed, entry)
+		}
+	}
+	return removed
+}
+
+func (s *syncState) inUse(document string) bool {
+	for _, entry := range s.Documents {
+		if entry.Document == document && s.seen[entry.key()] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *syncState) forget(entry stateEntry) {
+	i, ok := s.entries[entry.key()]
+	if !ok {
+		return
+	}
+	s.Documents = append(s.Documents[:i], s.Documents[i+1:]...)
+	s.entries = make(map[string]int)
+	for i, entry := range s.Documents {
+		s.entries[entry.key()] = i
+	}
+}
+
+func (s *syncState) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp := s.path + ".tmp"
+	if err := os.WriteFile(temp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, s.path)
+}
+
+var tempNamePattern = regexp.MustCompile(`^(oictl_[a-z]+_\d+|temp_[a-z]+_[0-9a-f]{8}-[0-9a-f-]{27}.*)$`)
+
+func sourceFileOrigin(filePath, root string) string {
+	parts := strings.Split(filepath.ToSlash(filePath), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if tempNamePattern.MatchString(parts[i]) {
+			return strings.Join(parts[i+1:], "/")
+		}
+	}
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(filePath)
+	}
+	if rel == "." {
+		return filepath.Base(filePath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func sourceFileRoot(filePath, origin string) string {
+	if origin == "" {
+		return filePath
+	}
+	return strings.TrimSuffix(filepath.ToSlash(filePath), "/"+origin)
+}
+
+func (s *syncState) markOrigin(tag, source, origin string) {
+	for _, entry := range s.Documents {
+		if entry.Tag == tag && entry.Source == source && entry.Path == origin {
+			s.seen[entry.key()] = true
+		}
+	}
+}
+
+func (s *syncState) markPresent(tag, source, root string) {
+	for _, entry := range s.Documents {
+		if entry.Tag != tag || entry.Source != source || s.seen[entry.key()] {
+			continue
+		}
+		if root == "" {
+			s.seen[entry.key()] = true
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(filepath.FromSlash(root), filepath.FromSlash(entry.Path))); err == nil {
+			s.seen[entry.key()] = true
+		}
+	}
+}
+
+func localSourceRoot(source DocumentSource, manifestPath string) string {
+	if source.Type != "" || !isLocalSource(source.Source) {
+		return ""
+	}
+	root, _ := filepath.Abs(filepath.Join(filepath.Dir(manifestPath), source.Source))
+	if stat, err := os.Stat(root); err == nil && !stat.IsDir() {
+		return filepath.Dir(root)
+	}
+	return root
+}
+
+func presentRoot(source DocumentSource, manifestPath string, files []sourceFile, skipped []skippedFile) string {
+	for _, file := range files {
+		if file.Root != "" {
+			return file.Root
+		}
+	}
+	for _, file := range skipped {
+		if file.Root != "" {
+			return file.Root
+		}
+	}
+	return localSourceRoot(source, manifestPath)
+}
+
+func (s *syncState) markRemovedSources(tag, manifestPath string, sources []DocumentSource) {
+	current := make(map[string]bool)
+	for _, source := range sources {
+		current[source.Source] = true
+	}
+	for _, entry := range s.Documents {
+		if entry.Tag != tag || current[entry.Source] {
+			continue
+		}
+		current[entry.Source] = true
+		if root := localSourceRoot(DocumentSource{Source: entry.Source}, manifestPath); root != "" {
+			s.markPresent(tag, entry.Source, root)
+		}
+	}
+}
+
+func fileEntry(file sourceFile, tag, source string) (stateEntry, error) {
+	entry := stateEntry{Tag: tag, Source: source, Path: file.Origin, Filename: file.Filename}
+	stat, err := os.Stat(file.Path)
+	if err != nil {
+		return entry, err
+	}
+	entry.ModTime = stat.ModTime().UTC()
+	entry.Size = stat.Size()
+	return entry, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+func forgeProject(source string) (*url.URL, bool) {
+	project, err := url.Parse(strings.TrimSuffix(strings.TrimSuffix(source, "/"), ".git"))
+	if err != nil || project.Scheme != "https" || strings.Count(strings.Trim(project.Path, "/"), "/") < 1 {
+		return nil, false
+	}
+	return project, project.Host == "github.com" || project.Host == "gitlab.com"
+}
+
+func downloadForgeArchive(source DocumentSource, project *url.URL, target string) error {
+	token := source.Token
+	if source.HttpsTokenRef != "" {
+		var err error
+		if token, err = resolveSecretRef(source.HttpsTokenRef); err != nil {
+			return err
+		}
+	}
+	registerSecret(token)
+
+	if project.Host == "gitlab.com" {
+		return downloadGitlabArchive(project, token, source.Ref, target)
+	}
+	archiveUrl := fmt.Sprintf("https://api.github.com/repos/%s/tarball", strings.Trim(project.Path, "/"))
+	if source.Ref != "" {
+		archiveUrl += "/" + url.PathEscape(source.Ref)
+	}
+	resp, err := githubRequest(token, "GET", archiveUrl, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return extractTarGz(resp.Body, target, 1)
+}
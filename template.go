@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+type manifestOptions struct {
+	Values         map[string]interface{}
+	Render         bool
+	Overlay        string
+	Patches        map[string][]map[string]interface{}
+	Selector       labelSelector
+	ResolveSecrets bool
+}
+
+type manifestFlags struct {
+	valueFiles stringList
+	setValues  stringList
+	overlay    string
+	selector   string
+}
+
+func addManifestFlags(fs *flag.FlagSet) *manifestFlags {
+	mf := &manifestFlags{}
+	fs.Var(&mf.valueFiles, "values", "values file used to render manifest templates (repeatable)")
+	fs.Var(&mf.setValues, "set", "set a template value, e.g. --set model.name=foo (repeatable)")
+	fs.StringVar(&mf.overlay, "overlay", "", "name of (or path to) an overlay directory patching the base manifests")
+	fs.StringVar(&mf.selector, "selector", "", "only use resources whose labels match, e.g. team=search,env=prod")
+	fs.StringVar(&mf.selector, "l", "", "shorthand for --selector")
+	return mf
+}
+
+func (mf *manifestFlags) options() (manifestOptions, error) {
+	values := make(map[string]interface{})
+	for _, file := range mf.valueFiles {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return manifestOptions{}, err
+		}
+		var fileValues map[string]interface{}
+		if err := yaml.Unmarshal(content, &fileValues); err != nil {
+			return manifestOptions{}, fmt.Errorf("failed to parse values file %s: %v", file, err)
+		}
+		mergeValues(values, fileValues)
+	}
+	for _, set := range mf.setValues {
+		if err := setValue(values, set); err != nil {
+			return manifestOptions{}, err
+		}
+	}
+	selector, err := parseSelector(mf.selector)
+	if err != nil {
+		return manifestOptions{}, err
+	}
+	render := len(mf.valueFiles) > 0 || len(mf.setValues) > 0
+	return manifestOptions{Values: values, Render: render, Overlay: mf.overlay, Selector: selector}, nil
+}
+
+func mergeValues(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeValues(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+func setValue(values map[string]interface{}, assignment string) error {
+	key, raw, ok := strings.Cut(assignment, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid --set value %q, expected key=value", assignment)
+	}
+
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(raw), &value); err != nil || value == nil {
+		value = raw
+	}
+
+	parts := strings.Split(key, ".")
+	current := values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[part] = next
+		}
+		current = next
+	}
+	current[parts[len(parts)-1]] = value
+	return nil
+}
+
+const missingValue = "<no value>"
+
+var templateFuncs = template.FuncMap{
+	"env": os.Getenv,
+	"default": func(def, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return def
+		}
+		return value
+	},
+	"quote": func(value interface{}) string {
+		return fmt.Sprintf("%q", fmt.Sprint(value))
+	},
+	"required": func(msg string, value interface{}) (interface{}, error) {
+		if value == nil || value == "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return value, nil
+	},
+}
+
+func renderManifest(filePath string, content []byte, opts manifestOptions) ([]byte, error) {
+	if !opts.Render || !bytes.Contains(content, []byte("{{")) {
+		return content, nil
+	}
+
+	tmpl, err := template.New(filePath).Funcs(templateFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %v", filePath, err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, map[string]interface{}{"Values": opts.Values}); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %v", filePath, err)
+	}
+	for i, line := range strings.Split(out.String(), "\n") {
+		if strings.Contains(line, missingValue) {
+			return nil, fmt.Errorf("failed to render template %s: line %d uses a value that is not set, pass it with --values or --set or use default", filePath, i+1)
+		}
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+type Tool struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Type        string `yaml:"type,omitempty"`
+		Name        string `yaml:"name,omitempty"`
+		Description string `yaml:"description,omitempty"`
+		File        string `yaml:"file,omitempty"`
+		Content     string `yaml:"content,omitempty"`
+		URL         string `yaml:"url,omitempty"`
+		Path        string `yaml:"path,omitempty"`
+		Auth        struct {
+			Type string `yaml:"type,omitempty"`
+			Key  string `yaml:"key,omitempty"`
+		} `yaml:"auth,omitempty"`
+		Valves          map[string]interface{} `yaml:"valves,omitempty"`
+		TokenUserValves map[string]interface{} `yaml:"tokenUserValves,omitempty"`
+	} `yaml:"spec"`
+}
+
+func (t Tool) toolType() string {
+	if t.Spec.Type != "" {
+		return t.Spec.Type
+	}
+	if t.Spec.URL != "" {
+		return "openapi"
+	}
+	return "native"
+}
+
+func (t Tool) displayName() string {
+	if t.Spec.Name != "" {
+		return t.Spec.Name
+	}
+	return t.Metadata.Name
+}
+
+func readSpecFile(manifestPath, file string) (string, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(manifestPath), file)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+func toolUrl(id, action string) string {
+	return fmt.Sprintf("%s/api/v1/tools/id/%s%s", BASE_URL, url.PathEscape(id), action)
+}
+
+func processNativeTool(config Tool, manifestPath string) error {
+	content := config.Spec.Content
+	if config.Spec.File != "" {
+		var err error
+		if content, err = readSpecFile(manifestPath, config.Spec.File); err != nil {
+			return err
+		}
+	}
+	if content == "" {
+		return fmt.Errorf("tool %s needs either spec.file or spec.content", config.Metadata.Name)
+	}
+
+	payload := map[string]interface{}{
+		"id":      config.Metadata.Name,
+		"name":    config.displayName(),
+		"content": content,
+		"meta": map[string]interface{}{
+			"description": config.Spec.Description,
+			"manifest":    map[string]interface{}{},
+			"labels":      config.Metadata.Labels,
+			"annotations": config.Metadata.Annotations,
+		},
+	}
+
+	if apiRequest("GET", toolUrl(config.Metadata.Name, ""), nil, nil) == nil {
+		if err := apiRequest("POST", toolUrl(config.Metadata.Name, "/update"), payload, nil); err != nil {
+			return err
+		}
+	} else if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/tools/create", BASE_URL), payload, nil); err != nil {
+		return err
+	}
+
+	return updateValves(func(action string) string {
+		return toolUrl(config.Metadata.Name, action)
+	}, config.Spec.Valves, config.Spec.TokenUserValves)
+}
+
+func getToolServers() ([]map[string]interface{}, error) {
+	var config struct {
+		Connections []map[string]interface{} `json:"TOOL_SERVER_CONNECTIONS"`
+	}
+	err := apiRequest("GET", fmt.Sprintf("%s/api/v1/configs/tool_servers", BASE_URL), nil, &config)
+	return config.Connections, err
+}
+
+func setToolServers(connections []map[string]interface{}) error {
+	payload := map[string]interface{}{"TOOL_SERVER_CONNECTIONS": connections}
+	return apiRequest("POST", fmt.Sprintf("%s/api/v1/configs/tool_servers", BASE_URL), payload, nil)
+}
+
+func processToolServer(config Tool) error {
+	authType := config.Spec.Auth.Type
+	if authType == "" {
+		authType = "none"
+		if config.Spec.Auth.Key != "" {
+			authType = "bearer"
+		}
+	}
+	path := config.Spec.Path
+	if path == "" {
+		path = "openapi.json"
+	}
+	registerSecret(config.Spec.Auth.Key)
+
+	connection := map[string]interface{}{
+		"url":       config.Spec.URL,
+		"path":      path,
+		"auth_type": authType,
+		"key":       config.Spec.Auth.Key,
+		"config":    map[string]interface{}{"enable": true},
+		"info": map[string]interface{}{
+			"id":          config.Metadata.Name,
+			"name":        config.displayName(),
+			"description": config.Spec.Description,
+		},
+	}
+
+	connections, err := getToolServers()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i, existing := range connections {
+		info, _ := existing["info"].(map[string]interface{})
+		if existing["url"] == config.Spec.URL || (info != nil && info["id"] == config.Metadata.Name) {
+			connections[i] = connection
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		connections = append(connections, connection)
+	}
+	return setToolServers(connections)
+}
+
+func processTool(config Tool, manifestPath string) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	switch config.toolType() {
+	case "native":
+		return processNativeTool(config, manifestPath)
+	case "openapi":
+		if len(config.Spec.Valves) > 0 || len(config.Spec.TokenUserValves) > 0 {
+			return fmt.Errorf("tool %s: valves are only supported for native tools", config.Metadata.Name)
+		}
+		return processToolServer(config)
+	}
+	return fmt.Errorf("unknown tool type %s", config.Spec.Type)
+}
+
+func deleteTool(config Tool) error {
+	if config.toolType() == "native" {
+		return apiRequest("DELETE", toolUrl(config.Metadata.Name, "/delete"), nil, nil)
+	}
+
+	connections, err := getToolServers()
+	if err != nil {
+		return err
+	}
+	kept := []map[string]interface{}{}
+	for _, existing := range connections {
+		info, _ := existing["info"].(map[string]interface{})
+		if existing["url"] == config.Spec.URL || (info != nil && info["id"] == config.Metadata.Name) {
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	return setToolServers(kept)
+}
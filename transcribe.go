@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	audioExtensions = []string{".mp3", ".mpga", ".m4a", ".wav", ".ogg", ".oga", ".opus", ".flac", ".aac"}
+	videoExtensions = []string{".mp4", ".mov", ".mkv", ".webm", ".avi", ".mpeg"}
+)
+
+type TranscriptionConfig struct {
+	URL       string `yaml:"url,omitempty"`
+	ApiKey    string `yaml:"apiKey,omitempty"`
+	ApiKeyEnv string `yaml:"apiKeyEnv,omitempty"`
+	Model     string `yaml:"model,omitempty"`
+	Language  string `yaml:"language,omitempty"`
+}
+
+func isMediaFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return containsString(audioExtensions, ext) || containsString(videoExtensions, ext)
+}
+
+func extractAudio(file, tempDir string) (string, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return file, nil
+	}
+	target := filepath.Join(tempDir, strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))+".mp3")
+	output, err := exec.Command("ffmpeg", "-y", "-loglevel", "error", "-i", file, "-vn", "-ac", "1", "-ar", "16000", target).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg %s: %v: %s", filepath.Base(file), err, strings.TrimSpace(string(output)))
+	}
+	return target, nil
+}
+
+func transcribeFile(file, filename string, config *TranscriptionConfig) (string, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/audio/transcriptions", BASE_URL)
+	token := TOKEN
+	if config != nil && config.URL != "" {
+		endpoint = strings.TrimSuffix(config.URL, "/") + "/audio/transcriptions"
+		key, err := resolveApiKey(config.ApiKey, config.ApiKeyEnv)
+		if err != nil {
+			return "", err
+		}
+		token = key
+	}
+
+	fields := make(map[string]string)
+	if config != nil && config.URL != "" {
+		fields["model"] = config.Model
+		if fields["model"] == "" {
+			fields["model"] = "whisper-1"
+		}
+		if config.Language != "" {
+			fields["language"] = config.Language
+		}
+	}
+	req, err := newFileUploadRequest(endpoint, file, filename, fields, false)
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to transcribe %s: %s - %s", filename, resp.Status, string(respBody))
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
+
+func transcribeMedia(file sourceFile, config *TranscriptionConfig, tempDir string) ([]sourceFile, error) {
+	audio := file.Path
+	if containsString(videoExtensions, strings.ToLower(filepath.Ext(file.Filename))) {
+		var err error
+		if audio, err = extractAudio(file.Path, tempDir); err != nil {
+			return nil, err
+		}
+	}
+	logf("Transcribing %s\n", file.Filename)
+	text, err := transcribeFile(audio, filepath.Base(audio), config)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename)) + ".md"
+	target := filepath.Join(tempDir, safeArchiveName(name))
+	doc := fmt.Sprintf("# %s\n\nTranscript of %s\n\n%s\n", strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename)), file.Filename, strings.TrimSpace(text))
+	if err := os.WriteFile(target, []byte(doc), 0644); err != nil {
+		return nil, err
+	}
+	return []sourceFile{{Path: target, Filename: name, Tags: file.Tags}}, nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+func resolveSource(source DocumentSource, manifestPath string) ([]sourceFile, []skippedFile, func(), error) {
+	files, cleanup, err := fetchSource(source, manifestPath)
+	if err != nil {
+		return nil, nil, cleanup, err
+	}
+	root, _ := filepath.Abs(filepath.Join(filepath.Dir(manifestPath), source.Source))
+	for i := range files {
+		files[i].Origin = sourceFileOrigin(files[i].Path, root)
+		files[i].Root = sourceFileRoot(files[i].Path, files[i].Origin)
+	}
+	files, skipped, err := filterSourceFiles(source, files)
+	if err != nil {
+		return nil, nil, cleanup, err
+	}
+	if err := applyFileTemplates(source, files); err != nil {
+		return nil, nil, cleanup, err
+	}
+	files, undecoded, decodeCleanup, err := transcodeSourceFiles(source, files)
+	fetchCleanup := cleanup
+	cleanup = func() { decodeCleanup(); fetchCleanup() }
+	if err != nil {
+		return nil, nil, cleanup, err
+	}
+	skipped = append(skipped, undecoded...)
+	applyFrontMatter(source.FrontMatter, files)
+	if !needsTransform(source, files) {
+		return files, skipped, cleanup, nil
+	}
+
+	tempDir, err := os.MkdirTemp("", "oictl_transform_")
+	if err != nil {
+		cleanup()
+		return nil, nil, func() {}, err
+	}
+	var transformed []sourceFile
+	for i, file := range files {
+		fileDir := filepath.Join(tempDir, strconv.Itoa(i))
+		if err := os.MkdirAll(fileDir, 0755); err != nil {
+			cleanup()
+			os.RemoveAll(tempDir)
+			return nil, nil, func() {}, err
+		}
+		var results []sourceFile
+		var err error
+		switch ext := strings.ToLower(filepath.Ext(file.Filename)); {
+		case (ext == ".csv" || ext == ".tsv") && source.SplitRows > 0:
+			results, err = splitCsvFile(file, source.SplitRows, fileDir)
+		case (ext == ".xlsx" || ext == ".ods" || isOfficeFile(file.Filename)) && source.PassthroughOffice:
+			results = []sourceFile{file}
+		case ext == ".xlsx" || ext == ".ods":
+			results, err = extractSpreadsheet(file, source.SplitRows, fileDir)
+		case isOfficeFile(file.Filename):
+			results, err = convertOfficeFile(file, fileDir)
+		case ext == ".mbox" || ext == ".eml":
+			results, err = extractMail(file, fileDir)
+		case (ext == ".yaml" || ext == ".yml" || ext == ".json") && source.SplitOpenapi:
+			results, err = splitOpenapiSpec(file, fileDir)
+		case isMediaFile(file.Filename) && source.Transcription != nil:
+			results, err = transcribeMedia(file, source.Transcription, fileDir)
+		case (ext == ".html" || ext == ".htm") && source.Site:
+			results, err = sitePageFile(file, filepath.Join(filepath.Dir(manifestPath), source.Source), fileDir)
+		case ext == ".html" || ext == ".htm":
+			results, err = htmlFileDocument(file, fileDir)
+		case ext == ".pdf" && (source.ExtractText || source.Ocr != nil):
+			results, err = extractPdfText(file, source, fileDir)
+		case isImageFile(file.Filename) && source.Ocr != nil:
+			results, err = ocrImageFile(file, source.Ocr, fileDir)
+		default:
+			results = []sourceFile{file}
+		}
+		if err != nil {
+			skipped = append(skipped, skippedFile{Name: file.Filename, Reason: err.Error(), Origin: file.Origin, Root: file.Root, Err: err})
+			continue
+		}
+		for j := range results {
+			results[j].Origin = file.Origin
+			results[j].Root = file.Root
+			results[j].Title = file.Title
+			results[j].Metadata = file.Metadata
+		}
+		transformed = append(transformed, results...)
+	}
+	return transformed, skipped, func() { os.RemoveAll(tempDir); cleanup() }, nil
+}
+
+func needsTransform(source DocumentSource, files []sourceFile) bool {
+	for _, file := range files {
+		if (isMediaFile(file.Filename) && source.Transcription != nil) || (isImageFile(file.Filename) && source.Ocr != nil) {
+			return true
+		}
+		switch strings.ToLower(filepath.Ext(file.Filename)) {
+		case ".csv", ".tsv":
+			if source.SplitRows > 0 {
+				return true
+			}
+		case ".xlsx", ".ods", ".docx", ".pptx", ".odt":
+			if !source.PassthroughOffice {
+				return true
+			}
+		case ".mbox", ".eml", ".html", ".htm":
+			return true
+		case ".yaml", ".yml", ".json":
+			if source.SplitOpenapi {
+				return true
+			}
+		case ".pdf":
+			if source.ExtractText || source.Ocr != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func splitCsvFile(file sourceFile, rowsPerDocument int, tempDir string) ([]sourceFile, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if strings.EqualFold(filepath.Ext(file.Filename), ".tsv") {
+		reader.Comma = '\t'
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", file.Filename, err)
+	}
+	if len(records) < 2 {
+		return []sourceFile{file}, nil
+	}
+
+	header, rows := records[0], records[1:]
+	base := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	var files []sourceFile
+	for start := 0; start < len(rows); start += rowsPerDocument {
+		end := start + rowsPerDocument
+		if end > len(rows) {
+			end = len(rows)
+		}
+		var doc strings.Builder
+		fmt.Fprintf(&doc, "# %s\n", file.Filename)
+		for i, row := range rows[start:end] {
+			fmt.Fprintf(&doc, "\n## Row %d\n\n", start+i+1)
+			for col, value := range row {
+				name := fmt.Sprintf("column %d", col+1)
+				if col < len(header) && header[col] != "" {
+					name = header[col]
+				}
+				fmt.Fprintf(&doc, "- %s: %s\n", name, value)
+			}
+		}
+
+		name := fmt.Sprintf("%s-row-%d.md", base, start+1)
+		if end-start > 1 {
+			name = fmt.Sprintf("%s-rows-%d-%d.md", base, start+1, end)
+		}
+		target := filepath.Join(tempDir, fmt.Sprintf("%d-%s", len(files), safeArchiveName(name)))
+		if err := os.WriteFile(target, []byte(doc.String()), 0644); err != nil {
+			return nil, err
+		}
+		files = append(files, sourceFile{Path: target, Filename: name, Tags: file.Tags})
+	}
+	return files, nil
+}
+
+type fileTemplateData struct {
+	Dir      string
+	Path     string
+	Filename string
+	Source   string
+	Repo     string
+	Commit   string
+	Modified string
+}
+
+var fileTemplateFuncs = template.FuncMap{
+	"base":  path.Base,
+	"dir":   path.Dir,
+	"ext":   path.Ext,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trimSuffix": func(suffix, s string) string {
+		return strings.TrimSuffix(s, suffix)
+	},
+}
+
+func fileTemplates(source DocumentSource) map[string]string {
+	templates := map[string]string{"tagTemplate": source.TagTemplate, "titleTemplate": source.TitleTemplate}
+	for key, value := range source.Metadata {
+		templates["metadata."+key] = value
+	}
+	return templates
+}
+
+func gitHead(dir string) string {
+	if useGoGit() {
+		return goGitHead(dir)
+	}
+	output, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func applyFileTemplates(source DocumentSource, files []sourceFile) error {
+	templates := make(map[string]*template.Template)
+	usesCommit := false
+	for name, text := range fileTemplates(source) {
+		if text == "" {
+			continue
+		}
+		tmpl, err := template.New(name).Delims("[[", "]]").Funcs(fileTemplateFuncs).Parse(text)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %v", name, text, err)
+		}
+		templates[name] = tmpl
+		usesCommit = usesCommit || strings.Contains(text, ".Commit")
+	}
+	if len(templates) == 0 {
+		return nil
+	}
+
+	repo := path.Base(strings.TrimSuffix(strings.TrimSuffix(source.Source, "/"), ".git"))
+	commit := ""
+	if usesCommit && len(files) > 0 {
+		commit = gitHead(filepath.Dir(files[0].Path))
+	}
+	for i, file := range files {
+		dir := path.Dir(file.Origin)
+		if dir == "." {
+			dir = ""
+		}
+		data := fileTemplateData{Dir: dir, Path: file.Origin, Filename: file.Filename, Source: source.Source, Repo: repo, Commit: commit}
+		if stat, err := os.Stat(file.Path); err == nil {
+			data.Modified = stat.ModTime().UTC().Format(time.RFC3339)
+		}
+		for name, tmpl := range templates {
+			var value strings.Builder
+			if err := tmpl.Execute(&value, data); err != nil {
+				return fmt.Errorf("failed to render %s for %s: %v", name, file.Filename, err)
+			}
+			v := strings.TrimSpace(value.String())
+			switch {
+			case name == "tagTemplate" && v != "":
+				files[i].Tags = append(files[i].Tags, v)
+			case name == "titleTemplate":
+				files[i].Title = v
+			case strings.HasPrefix(name, "metadata."):
+				if files[i].Metadata == nil {
+					files[i].Metadata = make(map[string]string)
+				}
+				files[i].Metadata[strings.TrimPrefix(name, "metadata.")] = v
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	compressUploads     atomic.Bool
+	reuseUploads        atomic.Bool
+	uploadBandwidth     = &bandwidthLimiter{}
+	collectionNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+type bandwidthLimiter struct {
+	mu             sync.Mutex
+	bytesPerSecond float64
+	next           time.Time
+}
+
+func (l *bandwidthLimiter) setLimit(limit string) error {
+	if limit == "" {
+		return nil
+	}
+	value := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(limit), "/s"), "/S")
+	size, err := parseFileSize(value)
+	if err != nil {
+		return fmt.Errorf("invalid --bwlimit %q, expected a rate like 5MB/s", limit)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bytesPerSecond = float64(size)
+	return nil
+}
+
+func (l *bandwidthLimiter) take(n int) {
+	l.mu.Lock()
+	if l.bytesPerSecond <= 0 || n <= 0 {
+		l.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / l.bytesPerSecond * float64(time.Second)))
+	l.mu.Unlock()
+	time.Sleep(delay)
+}
+
+type throttledReader struct {
+	io.Reader
+}
+
+func (r throttledReader) Read(p []byte) (int, error) {
+	if len(p) > 32<<10 {
+		p = p[:32<<10]
+	}
+	n, err := r.Reader.Read(p)
+	uploadBandwidth.take(n)
+	return n, err
+}
+
+type uploadProgress struct {
+	mu     sync.Mutex
+	loaded int
+}
+
+func (p *uploadProgress) uploaded() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loaded++
+	logf("\rDocuments loaded: %d", p.loaded)
+}
+
+func uploadSourceFiles(files []sourceFile, tags []string, metadata Metadata, source DocumentSource, replace map[string]string, parallel int, progress *uploadProgress) ([]uploadedDocument, []error) {
+	if parallel < 1 {
+		parallel = 1
+	}
+	docs := make([]uploadedDocument, len(files))
+	errs := make([]error, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel && w < len(files); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				file := files[i]
+				fileTags := append([]string{}, tags...)
+				for _, tag := range file.Tags {
+					if !containsString(fileTags, tag) {
+						fileTags = append(fileTags, tag)
+					}
+				}
+				docs[i], errs[i] = createDocument(file.Path, BASE_URL, fileTags, file.Filename, metadata, documentOptions{
+					Title:            file.Title,
+					CollectionPrefix: source.CollectionPrefix,
+					Replace:          replace[file.Filename],
+					Metadata:         file.Metadata,
+				})
+				if errs[i] == nil {
+					progress.uploaded()
+				}
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return docs, errs
+}
+
+func postFile(url, file, filename string, fields map[string]string) (*http.Response, error) {
+	compress := compressUploads.Load()
+	if compress {
+		contentType, _ := sniffContentType(file)
+		compress = strings.HasPrefix(contentType, "text/")
+	}
+	req, err := newFileUploadRequest(url, file, filename, fields, compress)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", TOKEN))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err == nil && compress && (resp.StatusCode == http.StatusUnsupportedMediaType || resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity) {
+		resp.Body.Close()
+		if compressUploads.CompareAndSwap(true, false) {
+			logf("\nThe server rejected a compressed upload (%s), uploading uncompressed\n", resp.Status)
+		}
+		return postFile(url, file, filename, fields)
+	}
+	return resp, err
+}
+
+func newFileUploadRequest(url, file, filename string, fields map[string]string, compress bool) (*http.Request, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	var envelope bytes.Buffer
+	writer := multipart.NewWriter(&envelope)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := writer.CreateFormFile("file", filename); err != nil {
+		return nil, err
+	}
+	head := append([]byte(nil), envelope.Bytes()...)
+	envelope.Reset()
+	writer.Close()
+	tail := envelope.Bytes()
+
+	if compress {
+		body := func() (io.ReadCloser, error) {
+			f, err := os.Open(file)
+			if err != nil {
+				return nil, err
+			}
+			pr, pw := io.Pipe()
+			go func() {
+				defer f.Close()
+				gz := gzip.NewWriter(pw)
+				_, err := io.Copy(gz, io.MultiReader(bytes.NewReader(head), f, bytes.NewReader(tail)))
+				if closeErr := gz.Close(); err == nil {
+					err = closeErr
+				}
+				pw.CloseWithError(err)
+			}()
+			return struct {
+				io.Reader
+				io.Closer
+			}{throttledReader{pr}, pr}, nil
+		}
+		first, err := body()
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest("POST", url, first)
+		if err != nil {
+			first.Close()
+			return nil, err
+		}
+		req.GetBody = body
+		req.ContentLength = -1
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Content-Encoding", "gzip")
+		return req, nil
+	}
+
+	body := func() (io.ReadCloser, error) {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{throttledReader{io.MultiReader(bytes.NewReader(head), f, bytes.NewReader(tail))}, f}, nil
+	}
+	first, err := body()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", url, first)
+	if err != nil {
+		first.Close()
+		return nil, err
+	}
+	req.GetBody = body
+	req.ContentLength = int64(len(head)) + stat.Size() + int64(len(tail))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req, nil
+}
+
+func collectionName(prefix, hash string) string {
+	name := strings.Trim(collectionNameChars.ReplaceAllString(prefix, "-"), "-._") + "-" + hash
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
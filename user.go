@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+type User struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Email           string   `yaml:"email,omitempty"`
+		Name            string   `yaml:"name"`
+		Role            string   `yaml:"role,omitempty"`
+		Password        string   `yaml:"password,omitempty"`
+		ProfileImageUrl string   `yaml:"profile_image_url,omitempty"`
+		Groups          []string `yaml:"groups,omitempty"`
+	} `yaml:"spec"`
+}
+
+type membershipPlan struct {
+	groups  map[string]bool
+	members map[string][]string
+}
+
+var plannedMemberships membershipPlan
+
+func planMemberships(manifests []manifest) membershipPlan {
+	plan := membershipPlan{groups: make(map[string]bool), members: make(map[string][]string)}
+	for _, m := range manifests {
+		switch c := m.Config.(type) {
+		case Group:
+			plan.groups[c.Metadata.Name] = true
+		case User:
+			for _, ref := range c.Spec.Groups {
+				plan.members[ref] = append(plan.members[ref], c.email())
+			}
+		}
+	}
+	return plan
+}
+
+func (p membershipPlan) groupMembers(group ServerGroup) []string {
+	members := p.members[group.Name]
+	if group.ID != group.Name {
+		members = append(members, p.members[group.ID]...)
+	}
+	return members
+}
+
+func (u User) email() string {
+	if u.Spec.Email != "" {
+		return strings.ToLower(u.Spec.Email)
+	}
+	return strings.ToLower(u.Metadata.Name)
+}
+
+func findUser(email string) (ServerUser, bool, error) {
+	users, err := getUsers()
+	if err != nil {
+		return ServerUser{}, false, err
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Email, email) {
+			return user, true, nil
+		}
+	}
+	return ServerUser{}, false, nil
+}
+
+func randomPassword() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func processUser(config User) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	email := config.email()
+	role := config.Spec.Role
+	if role == "" {
+		role = "user"
+	}
+	switch role {
+	case "admin", "user", "pending":
+	default:
+		return fmt.Errorf("user %s has unknown role %s", email, role)
+	}
+	registerSecret(config.Spec.Password)
+
+	user, exists, err := findUser(email)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		password := config.Spec.Password
+		if password == "" {
+			if password, err = randomPassword(); err != nil {
+				return err
+			}
+			registerSecret(password)
+		}
+		payload := map[string]interface{}{
+			"name":     config.Spec.Name,
+			"email":    email,
+			"password": password,
+			"role":     role,
+		}
+		if config.Spec.ProfileImageUrl != "" {
+			payload["profile_image_url"] = config.Spec.ProfileImageUrl
+		}
+		if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/auths/add", BASE_URL), payload, &user); err != nil {
+			return err
+		}
+	} else {
+		profileImageUrl := config.Spec.ProfileImageUrl
+		if profileImageUrl == "" {
+			profileImageUrl = user.ProfileImageUrl
+		}
+		payload := map[string]interface{}{
+			"name":              config.Spec.Name,
+			"email":             email,
+			"profile_image_url": profileImageUrl,
+		}
+		if config.Spec.Password != "" {
+			payload["password"] = config.Spec.Password
+		}
+		if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/users/%s/update", BASE_URL, user.ID), payload, nil); err != nil {
+			return err
+		}
+		if user.Role != role {
+			payload := map[string]string{"id": user.ID, "role": role}
+			if err := apiRequest("POST", fmt.Sprintf("%s/api/v1/users/update/role", BASE_URL), payload, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(config.Spec.Groups) == 0 {
+		return nil
+	}
+	groups, err := getGroups()
+	if err != nil {
+		return err
+	}
+	for _, ref := range config.Spec.Groups {
+		if _, err := resolveGroupIDs([]string{ref}, groups); err != nil && !plannedMemberships.groups[ref] {
+			return err
+		}
+	}
+	for _, group := range groups {
+		if !containsString(config.Spec.Groups, group.ID) && !containsString(config.Spec.Groups, group.Name) {
+			continue
+		}
+		if containsString(group.UserIDs, user.ID) {
+			continue
+		}
+		group.UserIDs = append(group.UserIDs, user.ID)
+		if err := updateGroup(group); err != nil {
+			return fmt.Errorf("failed to add %s to group %s: %v", email, group.Name, err)
+		}
+	}
+	return nil
+}
+
+func updateGroup(group ServerGroup) error {
+	payload := map[string]interface{}{
+		"name":        group.Name,
+		"description": group.Description,
+		"permissions": group.Permissions,
+		"user_ids":    group.UserIDs,
+	}
+	return apiRequest("POST", fmt.Sprintf("%s/api/v1/groups/id/%s/update", BASE_URL, group.ID), payload, nil)
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func deleteUser(config User) error {
+	user, exists, err := findUser(config.email())
+	if err != nil || !exists {
+		return err
+	}
+	return apiRequest("DELETE", fmt.Sprintf("%s/api/v1/users/%s", BASE_URL, user.ID), nil, nil)
+}
@@ -0,0 +1,21 @@
+package main
+
+func updateValves(resourceUrl func(action string) string, valves, tokenUserValves map[string]interface{}) error {
+	for action, values := range map[string]map[string]interface{}{"/valves": valves, "/valves/user": tokenUserValves} {
+		if len(values) == 0 {
+			continue
+		}
+		var current map[string]interface{}
+		if err := apiRequest("GET", resourceUrl(action), nil, &current); err != nil {
+			return err
+		}
+		if current == nil {
+			current = make(map[string]interface{})
+		}
+		mergeValues(current, values)
+		if err := apiRequest("POST", resourceUrl(action+"/update"), current, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
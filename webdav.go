@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type davMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+type webdavClient struct {
+	username string
+	password string
+}
+
+func (c webdavClient) request(method, rawUrl string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, rawUrl, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	if method == "PROPFIND" {
+		req.Header.Set("Depth", "1")
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s %s: %s - %s", method, rawUrl, resp.Status, string(respBody))
+	}
+	return resp, nil
+}
+
+func (c webdavClient) list(dirUrl *url.URL) ([]string, error) {
+	resp, err := c.request("PROPFIND", dirUrl.String(), strings.NewReader(`<?xml version="1.0"?><d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/></d:prop></d:propfind>`))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var status davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("invalid PROPFIND response from %s: %v", dirUrl, err)
+	}
+
+	var files []string
+	for _, r := range status.Responses {
+		href, err := dirUrl.Parse(r.Href)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSuffix(href.Path, "/") == strings.TrimSuffix(dirUrl.Path, "/") {
+			continue
+		}
+		collection := false
+		for _, propstat := range r.Propstat {
+			collection = collection || propstat.Prop.ResourceType.Collection != nil
+		}
+		if !collection {
+			files = append(files, href.String())
+			continue
+		}
+		if !strings.HasSuffix(href.Path, "/") {
+			href.Path += "/"
+		}
+		nested, err := c.list(href)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, nested...)
+	}
+	return files, nil
+}
+
+func (c webdavClient) download(rawUrl, target string) error {
+	resp, err := c.request("GET", rawUrl, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
+
+func handleWebdavSource(source DocumentSource) ([]string, string, error) {
+	base, err := url.Parse(source.Source)
+	if err != nil || base.Host == "" {
+		return nil, "", fmt.Errorf("invalid WebDAV source %s", source.Source)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	registerSecret(source.Password)
+	client := webdavClient{username: source.Username, password: source.Password}
+
+	dirs := []*url.URL{base}
+	if len(source.Dir) > 0 {
+		dirs = nil
+		for _, dir := range source.Dir {
+			dirUrl := *base
+			dirUrl.Path = strings.TrimSuffix(path.Join(base.Path, dir), "/") + "/"
+			dirs = append(dirs, &dirUrl)
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "oictl_webdav_")
+	if err != nil {
+		return nil, "", err
+	}
+	var files []string
+	for _, dir := range dirs {
+		remoteFiles, err := client.list(dir)
+		if err != nil {
+			os.RemoveAll(tempDir)
+			return nil, "", err
+		}
+		for _, remote := range remoteFiles {
+			remoteUrl, _ := url.Parse(remote)
+			relative := strings.TrimPrefix(remoteUrl.Path, base.Path)
+			if len(source.Extensions) > 0 && !hasExtension(relative, source.Extensions) {
+				continue
+			}
+			target := filepath.Join(tempDir, filepath.FromSlash(path.Clean("/"+relative)))
+			if err := client.download(remote, target); err != nil {
+				os.RemoveAll(tempDir)
+				return nil, "", err
+			}
+			files = append(files, target)
+		}
+	}
+	return files, tempDir, nil
+}
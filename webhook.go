@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Webhook struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		URL     string            `yaml:"url"`
+		Format  string            `yaml:"format,omitempty"`
+		On      string            `yaml:"on,omitempty"`
+		Headers map[string]string `yaml:"headers,omitempty"`
+	} `yaml:"spec"`
+}
+
+type resourceResult struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type applyReport struct {
+	Event     string           `json:"event"`
+	Server    string           `json:"server"`
+	Completed string           `json:"completed"`
+	Aborted   string           `json:"aborted,omitempty"`
+	Documents int              `json:"documents"`
+	Unchanged int              `json:"unchanged"`
+	Pruned    int              `json:"pruned"`
+	Applied   []resourceResult `json:"applied"`
+	Failed    []resourceResult `json:"failed"`
+	Skipped   []skippedFile    `json:"skipped"`
+}
+
+func (r *applyReport) applied(kind, name, action string) {
+	r.Applied = append(r.Applied, resourceResult{Kind: kind, Name: name, Action: action})
+}
+
+func (r applyReport) count(action string) int {
+	n := 0
+	for _, a := range r.Applied {
+		if a.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+func resourceAction(m manifest) string {
+	var exists bool
+	var err error
+	switch c := m.Config.(type) {
+	case Model:
+		exists = apiRequest("GET", fmt.Sprintf("%s/api/v1/models/model?id=%s", BASE_URL, url.QueryEscape(c.Metadata.Name)), nil, nil) == nil
+	case Prompt:
+		exists = promptExists(c.command())
+	case Tool:
+		if c.toolType() != "native" {
+			return ""
+		}
+		exists = apiRequest("GET", toolUrl(c.Metadata.Name, ""), nil, nil) == nil
+	case Function:
+		exists = apiRequest("GET", functionUrl(c.Metadata.Name, ""), nil, nil) == nil
+	case Knowledge:
+		_, exists, err = findKnowledgeBase(c.Metadata.Name)
+	case User:
+		_, exists, err = findUser(c.email())
+	case Group:
+		_, exists, err = findGroup(c.Metadata.Name)
+	case InstanceConfig, RAGConfig, WebSearchConfig, ImageGenerationConfig, AudioConfig, AuthProvider:
+		exists = true
+	default:
+		return ""
+	}
+	if err != nil {
+		return ""
+	}
+	if exists {
+		return "updated"
+	}
+	return "created"
+}
+
+var secretHeaderNames = regexp.MustCompile(`(?i)authorization|cookie|token|secret|signature|password|api[_-]?key`)
+
+func (r *applyReport) failed(kind, name string, err error) {
+	r.Failed = append(r.Failed, resourceResult{Kind: kind, Name: name, Error: redact(err.Error())})
+}
+
+func (r applyReport) summary() string {
+	status := "completed"
+	if r.Aborted != "" {
+		status = "aborted: " + r.Aborted
+	}
+	lines := []string{fmt.Sprintf("oictl apply to %s %s: %d applied (%d created, %d updated), %d failed, %d documents uploaded, %d unchanged, %d pruned, %d skipped",
+		r.Server, status, len(r.Applied), r.count("created"), r.count("updated"), len(r.Failed), r.Documents, r.Unchanged, r.Pruned, len(r.Skipped))}
+	for _, f := range r.Failed {
+		lines = append(lines, fmt.Sprintf("• %s %s: %s", f.Kind, f.Name, f.Error))
+	}
+	for _, s := range r.Skipped {
+		lines = append(lines, fmt.Sprintf("• skipped %s: %s", s.Name, s.Reason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sendWebhook(webhook Webhook, report applyReport) error {
+	var payload interface{}
+	switch webhook.Spec.Format {
+	case "", "json":
+		payload = report
+	case "slack":
+		payload = map[string]string{"text": report.summary()}
+	default:
+		return fmt.Errorf("unknown webhook format %s", webhook.Spec.Format)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", webhook.Spec.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range webhook.Spec.Headers {
+		if secretHeaderNames.MatchString(key) {
+			registerSecret(value)
+		}
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s - %s", resp.Status, string(respBody))
+	}
+	return nil
+}
+
+func notifyWebhooks(webhooks []Webhook, report applyReport) {
+	report.Event = "apply.completed"
+	report.Completed = time.Now().UTC().Format(time.RFC3339)
+	if report.Applied == nil {
+		report.Applied = []resourceResult{}
+	}
+	if report.Failed == nil {
+		report.Failed = []resourceResult{}
+	}
+	if report.Skipped == nil {
+		report.Skipped = []skippedFile{}
+	}
+	for _, webhook := range webhooks {
+		if webhook.Spec.On == "failure" && len(report.Failed) == 0 && report.Aborted == "" {
+			continue
+		}
+		if err := sendWebhook(webhook, report); err != nil {
+			logf("Error calling webhook %s: %v\n", webhook.Metadata.Name, err)
+		}
+	}
+}
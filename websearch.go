@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type WebSearchConfig struct {
+	Kind     string   `yaml:"kind"`
+	Metadata Metadata `yaml:"metadata"`
+	Spec     struct {
+		Enabled            *bool    `yaml:"enabled,omitempty"`
+		Engine             string   `yaml:"engine"`
+		ApiKey             string   `yaml:"apiKey,omitempty"`
+		ApiKeyEnv          string   `yaml:"apiKeyEnv,omitempty"`
+		Url                string   `yaml:"url,omitempty"`
+		EngineID           string   `yaml:"engineId,omitempty"`
+		ResultCount        *int     `yaml:"resultCount,omitempty"`
+		ConcurrentRequests *int     `yaml:"concurrentRequests,omitempty"`
+		DomainFilter       []string `yaml:"domainFilter,omitempty"`
+	} `yaml:"spec"`
+}
+
+var webSearchKeyFields = map[string]string{
+	"google_pse": "google_pse_api_key",
+	"brave":      "brave_search_api_key",
+	"serpstack":  "serpstack_api_key",
+	"serper":     "serper_api_key",
+	"serply":     "serply_api_key",
+	"tavily":     "tavily_api_key",
+	"searchapi":  "searchapi_api_key",
+	"jina":       "jina_api_key",
+	"bing":       "bing_search_v7_subscription_key",
+	"searxng":    "",
+	"duckduckgo": "",
+}
+
+func resolveApiKey(key, keyEnv string) (string, error) {
+	if keyEnv != "" {
+		key = os.Getenv(keyEnv)
+		if key == "" {
+			return "", fmt.Errorf("environment variable %s for API key is not set", keyEnv)
+		}
+	}
+	registerSecret(key)
+	return key, nil
+}
+
+func processWebSearchConfig(config WebSearchConfig) error {
+	if TOKEN == "" {
+		return fmt.Errorf("OI_TOKEN environment variable is not set")
+	}
+
+	keyField, ok := webSearchKeyFields[config.Spec.Engine]
+	if config.Spec.Engine != "" && !ok {
+		return fmt.Errorf("unknown web search engine %s", config.Spec.Engine)
+	}
+	key, err := resolveApiKey(config.Spec.ApiKey, config.Spec.ApiKeyEnv)
+	if err != nil {
+		return err
+	}
+	if key != "" && keyField == "" {
+		return fmt.Errorf("web search engine %s does not use an API key", config.Spec.Engine)
+	}
+
+	search := map[string]interface{}{
+		"enabled":             config.Spec.Enabled,
+		"engine":              config.Spec.Engine,
+		"result_count":        config.Spec.ResultCount,
+		"concurrent_requests": config.Spec.ConcurrentRequests,
+	}
+	if keyField != "" {
+		search[keyField] = key
+	}
+	switch config.Spec.Engine {
+	case "searxng":
+		search["searxng_query_url"] = config.Spec.Url
+	case "google_pse":
+		search["google_pse_engine_id"] = config.Spec.EngineID
+	case "searchapi":
+		search["searchapi_engine"] = config.Spec.EngineID
+	}
+	if config.Spec.DomainFilter != nil {
+		search["domain_filter_list"] = config.Spec.DomainFilter
+	}
+
+	fields := map[string]interface{}{
+		"web": map[string]interface{}{"search": search},
+	}
+	return updateServerConfig("/api/v1/retrieval/config", "/api/v1/retrieval/config/update", fields)
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlTitlePattern       = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlBoilerplatePattern = regexp.MustCompile(`(?is)<(script|style|noscript|nav|header|footer|aside|form|svg|template)\b[^>]*>.*?</(script|style|noscript|nav|header|footer|aside|form|svg|template)>|<!--.*?-->`)
+	htmlMainPattern        = regexp.MustCompile(`(?is)<(main|article)\b[^>]*>(.*)</(main|article)>`)
+	htmlRoleMainPattern    = regexp.MustCompile(`(?is)<[a-z]+\b[^>]*\brole=["']main["'][^>]*>(.*)`)
+	htmlContentEndPattern  = regexp.MustCompile(`(?is)<div\b[^>]*class=["'][^"']*\b(sphinxsidebar|rst-footer-buttons|related)\b|<footer\b`)
+	htmlHeaderLinkPattern  = regexp.MustCompile(`(?is)<a\b[^>]*class=["'][^"']*\bheaderlink\b[^>]*>.*?</a>`)
+	htmlBodyPattern        = regexp.MustCompile(`(?is)<body\b[^>]*>(.*)</body>`)
+	htmlHeadingPattern     = regexp.MustCompile(`(?is)<h([1-6])\b[^>]*>(.*?)</h[1-6]>`)
+	htmlPrePattern         = regexp.MustCompile(`(?is)<pre\b[^>]*>(.*?)</pre>`)
+	htmlCodePattern        = regexp.MustCompile(`(?is)<code\b[^>]*>(.*?)</code>`)
+	htmlAnchorPattern      = regexp.MustCompile(`(?is)<a\b[^>]*?\bhref=["']([^"']*)["'][^>]*>(.*?)</a>`)
+	htmlStrongPattern      = regexp.MustCompile(`(?is)<(?:strong|b)\b[^>]*>(.*?)</(?:strong|b)>`)
+	htmlEmphasisPattern    = regexp.MustCompile(`(?is)<(?:em|i)\b[^>]*>(.*?)</(?:em|i)>`)
+	htmlListItemPattern    = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlCellPattern        = regexp.MustCompile(`(?i)</t[dh]>\s*<t[dh]\b[^>]*>`)
+	htmlRowStartPattern    = regexp.MustCompile(`(?i)<tr\b[^>]*>`)
+	htmlRowEndPattern      = regexp.MustCompile(`(?i)</tr>`)
+	htmlBlockPattern       = regexp.MustCompile(`(?i)</?(p|div|section|br|ul|ol|table|pre|blockquote|dd|dt)\b[^>]*>`)
+	htmlTagPattern         = regexp.MustCompile(`(?s)<[^>]+>`)
+	blankLinesPattern      = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+	spacesPattern          = regexp.MustCompile(`[ \t]+`)
+	lineIndentPattern      = regexp.MustCompile(`(?m)^ +`)
+	listGapPattern         = regexp.MustCompile(`(?m)^((?:- |\| )[^\n]*)\n\n+((?:- |\| ))`)
+)
+
+func isSitemapSource(source string) bool {
+	u, err := url.Parse(source)
+	return err == nil && isUrlSource(source) && strings.Contains(path.Base(u.Path), "sitemap") &&
+		(strings.HasSuffix(u.Path, ".xml") || strings.HasSuffix(u.Path, ".xml.gz"))
+}
+
+func htmlInlineText(fragment string) string {
+	return strings.TrimSpace(spacesPattern.ReplaceAllString(htmlTagPattern.ReplaceAllString(fragment, ""), " "))
+}
+
+func htmlToMarkdown(page, baseUrl string) (string, string) {
+	title := ""
+	if match := htmlTitlePattern.FindStringSubmatch(page); match != nil {
+		title = strings.TrimSpace(html.UnescapeString(htmlTagPattern.ReplaceAllString(match[1], "")))
+	}
+
+	content := htmlHeaderLinkPattern.ReplaceAllString(page, "")
+	if match := htmlRoleMainPattern.FindStringSubmatch(content); match != nil {
+		content = match[1]
+		if end := htmlContentEndPattern.FindStringIndex(content); end != nil {
+			content = content[:end[0]]
+		}
+	}
+	content = htmlBoilerplatePattern.ReplaceAllString(content, "")
+	if match := htmlMainPattern.FindStringSubmatch(content); match != nil {
+		content = match[2]
+	} else if match := htmlBodyPattern.FindStringSubmatch(content); match != nil {
+		content = match[1]
+	}
+
+	var codeBlocks []string
+	content = htmlPrePattern.ReplaceAllStringFunc(content, func(pre string) string {
+		code := html.UnescapeString(htmlTagPattern.ReplaceAllString(htmlPrePattern.FindStringSubmatch(pre)[1], ""))
+		codeBlocks = append(codeBlocks, "```\n"+strings.Trim(code, "\n")+"\n```")
+		return fmt.Sprintf("\n\n\x00code%d\x00\n\n", len(codeBlocks)-1)
+	})
+	content = htmlHeadingPattern.ReplaceAllStringFunc(content, func(heading string) string {
+		match := htmlHeadingPattern.FindStringSubmatch(heading)
+		return fmt.Sprintf("\n\n%s %s\n\n", strings.Repeat("#", len(match[1])), htmlInlineText(match[2]))
+	})
+	content = htmlCodePattern.ReplaceAllStringFunc(content, func(code string) string {
+		if text := htmlInlineText(htmlCodePattern.FindStringSubmatch(code)[1]); text != "" {
+			return "`" + text + "`"
+		}
+		return ""
+	})
+	content = htmlAnchorPattern.ReplaceAllStringFunc(content, func(link string) string {
+		match := htmlAnchorPattern.FindStringSubmatch(link)
+		text := htmlInlineText(match[2])
+		href := html.UnescapeString(strings.TrimSpace(match[1]))
+		if strings.HasPrefix(href, "#") {
+			return text
+		}
+		if base, err := url.Parse(baseUrl); err == nil && baseUrl != "" {
+			if resolved, err := base.Parse(href); err == nil {
+				href = resolved.String()
+			}
+		}
+		if text == "" || !isUrlSource(href) {
+			return text
+		}
+		return fmt.Sprintf("[%s](%s)", text, href)
+	})
+	content = htmlStrongPattern.ReplaceAllStringFunc(content, func(strong string) string {
+		if text := htmlInlineText(htmlStrongPattern.FindStringSubmatch(strong)[1]); text != "" {
+			return "**" + text + "**"
+		}
+		return ""
+	})
+	content = htmlEmphasisPattern.ReplaceAllStringFunc(content, func(emphasis string) string {
+		if text := htmlInlineText(htmlEmphasisPattern.FindStringSubmatch(emphasis)[1]); text != "" {
+			return "_" + text + "_"
+		}
+		return ""
+	})
+	content = htmlListItemPattern.ReplaceAllString(content, "\n- ")
+	content = htmlRowStartPattern.ReplaceAllString(content, "\n| ")
+	content = htmlRowEndPattern.ReplaceAllString(content, " |\n")
+	content = htmlCellPattern.ReplaceAllString(content, " | ")
+	content = htmlBlockPattern.ReplaceAllString(content, "\n")
+	content = html.UnescapeString(htmlTagPattern.ReplaceAllString(content, ""))
+	content = spacesPattern.ReplaceAllString(content, " ")
+	content = lineIndentPattern.ReplaceAllString(content, "")
+	content = blankLinesPattern.ReplaceAllString(content, "\n\n")
+	for listGapPattern.MatchString(content) {
+		content = listGapPattern.ReplaceAllString(content, "$1\n$2")
+	}
+	for i, block := range codeBlocks {
+		content = strings.Replace(content, fmt.Sprintf("\x00code%d\x00", i), block, 1)
+	}
+	return title, strings.TrimSpace(content)
+}
+
+func pageFilename(pageUrl string) string {
+	u, err := url.Parse(pageUrl)
+	if err != nil {
+		return safeArchiveName(pageUrl) + ".md"
+	}
+	name := strings.Trim(strings.TrimSuffix(u.Path, path.Ext(u.Path)), "/")
+	if name == "" {
+		name = "index"
+	}
+	return safeArchiveName(u.Host+"_"+strings.ReplaceAll(name, "/", "_")) + ".md"
+}
+
+func htmlFileDocument(file sourceFile, tempDir string) ([]sourceFile, error) {
+	content, err := os.ReadFile(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	title, text := htmlToMarkdown(string(content), "")
+	if title == "" {
+		title = base
+	}
+	target := filepath.Join(tempDir, safeArchiveName(base+".md"))
+	if err := os.WriteFile(target, []byte("# "+title+"\n\n"+text+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return []sourceFile{{Path: target, Filename: base + ".md", Tags: file.Tags}}, nil
+}
+
+func pageDocument(pageUrl, page string) string {
+	title, text := htmlToMarkdown(page, pageUrl)
+	if title == "" {
+		title = pageUrl
+	}
+	return fmt.Sprintf("# %s\n\nSource: %s\n\n%s\n", title, pageUrl, text)
+}
+
+func sitemapUrls(sitemapUrl string, headers map[string]string, seen map[string]bool) ([]string, error) {
+	if seen[sitemapUrl] {
+		return nil, nil
+	}
+	seen[sitemapUrl] = true
+
+	content, err := fetchUrlContent(sitemapUrl, headers)
+	if err != nil {
+		return nil, err
+	}
+	data := []byte(content)
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, err
+		}
+	}
+
+	var sitemap struct {
+		XMLName  xml.Name
+		Urls     []string `xml:"url>loc"`
+		Sitemaps []string `xml:"sitemap>loc"`
+	}
+	if err := xml.Unmarshal(data, &sitemap); err != nil {
+		return nil, fmt.Errorf("invalid sitemap %s: %v", sitemapUrl, err)
+	}
+	var urls []string
+	for _, loc := range sitemap.Urls {
+		urls = append(urls, strings.TrimSpace(loc))
+	}
+	for _, loc := range sitemap.Sitemaps {
+		nested, err := sitemapUrls(strings.TrimSpace(loc), headers, seen)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, nested...)
+	}
+	return urls, nil
+}
+
+func matchesPathPrefix(pageUrl string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	u, err := url.Parse(pageUrl)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(u.Path, "/"+strings.TrimPrefix(prefix, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func handleSitemapSource(source DocumentSource) ([]sourceFile, string, error) {
+	headers, err := urlHeaders(source)
+	if err != nil {
+		return nil, "", err
+	}
+	listed, err := sitemapUrls(source.Source, headers, make(map[string]bool))
+	if err != nil {
+		return nil, "", err
+	}
+	var urls []string
+	for _, pageUrl := range listed {
+		if matchesPathPrefix(pageUrl, source.Dir) {
+			urls = append(urls, pageUrl)
+		}
+	}
+	tempDir, err := os.MkdirTemp("", "oictl_sitemap_")
+	if err != nil {
+		return nil, "", err
+	}
+
+	var files []sourceFile
+	for _, pageUrl := range urls {
+		page, err := fetchUrlContent(pageUrl, headers)
+		if err != nil {
+			logf("Error fetching %s: %v\n", pageUrl, err)
+			continue
+		}
+		name := pageFilename(pageUrl)
+		target := filepath.Join(tempDir, name)
+		if err := os.WriteFile(target, []byte(pageDocument(pageUrl, page)), 0644); err != nil {
+			os.RemoveAll(tempDir)
+			return nil, "", err
+		}
+		files = append(files, sourceFile{Path: target, Filename: name})
+		logf("\rPages fetched: %d/%d", len(files), len(urls))
+	}
+	if len(files) > 0 {
+		logf("\n")
+	}
+	return files, tempDir, nil
+}
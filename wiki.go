@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func isWikiSource(source string) bool {
+	return strings.HasPrefix(source, "wiki:")
+}
+
+func wikiRepoUrl(source string) (string, error) {
+	repo := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(source, "wiki:"), "/"), ".git")
+	switch {
+	case repo == "":
+		return "", fmt.Errorf("invalid source %s, expected wiki:owner/repo or wiki:<repository url>", source)
+	case strings.HasSuffix(repo, ".wiki"):
+		return repo + ".git", nil
+	case strings.Contains(repo, "://") || strings.HasPrefix(repo, "git@"):
+		return repo + ".wiki.git", nil
+	case strings.Count(repo, "/") == 1:
+		return fmt.Sprintf("https://github.com/%s.wiki.git", repo), nil
+	default:
+		return fmt.Sprintf("https://%s.wiki.git", repo), nil
+	}
+}
+
+func handleWikiSource(source DocumentSource) ([]string, string, error) {
+	repoUrl, err := wikiRepoUrl(source.Source)
+	if err != nil {
+		return nil, "", err
+	}
+	source.Source = repoUrl
+	if len(source.Dir) == 0 {
+		source.Dir = []string{"."}
+	}
+	return handleGitSource(source)
+}